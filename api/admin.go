@@ -0,0 +1,30 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// adminOnly lets through requests carrying ADMIN_TOKEN
+// as a bearer token. Admin routes are closed when no
+// token is configured
+func (s *Server) adminOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.AdminToken == "" {
+			return echo.NewHTTPError(http.StatusForbidden, errors.New("Admin API is disabled"))
+		}
+		header := c.Request().Header.Get(echo.HeaderAuthorization)
+		if !strings.HasPrefix(header, "Bearer ") {
+			return echo.NewHTTPError(http.StatusUnauthorized, errors.New("Invalid admin token"))
+		}
+		token := strings.TrimPrefix(header, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) != 1 {
+			return echo.NewHTTPError(http.StatusUnauthorized, errors.New("Invalid admin token"))
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/auth"
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+const (
+	otpTTL         = 5 * time.Minute
+	otpMaxAttempts = 5
+	phoneKey       = "phone"
+)
+
+// otpLimits lets a phone number have a code every 30 seconds
+// and 5 codes an hour, the 5 attempts being shared by them
+var otpLimits = models.OTPLimits{ResendAfter: 30 * time.Second, Window: time.Hour, MaxSends: 5}
+
+type otpRequest struct {
+	Phone string `json:"telefone"`
+	Code  string `json:"codigo"`
+}
+
+// RequestOTP sends a one time password by SMS to the phone number
+// POST /auth/otp {"telefone": "+5521999999999"}
+//
+// HTTP responses:
+// 202 accepted
+// 400 bad request
+// 429 too many requests
+// 500 internal server error
+func (s *Server) RequestOTP(c echo.Context) (err error) {
+	var req otpRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	phone, err := auth.NormalizePhone(req.Phone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	code, err := auth.NewCode()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	now := time.Now()
+	otp := models.OTP{Phone: phone, CodeHash: auth.HashCode(s.OTPKey, phone, code), SentAt: now, ExpiresAt: now.Add(otpTTL)}
+	if err = s.OTPs.Issue(otp, otpLimits); err == models.ErrOTPLimited {
+		return echo.NewHTTPError(http.StatusTooManyRequests, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if err = s.SMS.Send(phone, "Seu código Mudae é "+code); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+// VerifyOTP exchanges a valid code for a session token
+// POST /auth/verify {"telefone": "+5521999999999", "codigo": "123456"}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) VerifyOTP(c echo.Context) (err error) {
+	var req otpRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	phone, err := auth.NormalizePhone(req.Phone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	invalid := echo.NewHTTPError(http.StatusUnauthorized, errors.New("Invalid or expired code"))
+	otp, err := s.OTPs.Attempt(phone)
+	if err == models.ErrOTPNotFound {
+		return invalid
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if otp.Attempts >= otpMaxAttempts || time.Now().After(otp.ExpiresAt) || !auth.CheckCode(s.OTPKey, phone, req.Code, otp.CodeHash) {
+		return invalid
+	}
+	// deleting makes the code single use even if verified twice at once
+	if err = s.OTPs.Delete(phone); err == models.ErrOTPNotFound {
+		return invalid
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	token, claims, err := s.Sessions.Issue(phone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"token":     token,
+		"telefone":  phone,
+		"expira_em": time.Unix(claims.ExpiresAt, 0),
+	})
+}
+
+// authenticated requires a valid session token
+// in the Authorization header
+func (s *Server) authenticated(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get(echo.HeaderAuthorization)
+		if !strings.HasPrefix(header, "Bearer ") {
+			return echo.NewHTTPError(http.StatusUnauthorized, auth.ErrInvalidToken)
+		}
+		claims, err := s.Sessions.Parse(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		}
+		c.Set(phoneKey, claims.Subject)
+		return next(c)
+	}
+}
+
+// ownsProfile lets the user reach only the profile registered
+// with their phone number
+func (s *Server) ownsProfile(next echo.HandlerFunc) echo.HandlerFunc {
+	return s.authenticated(func(c echo.Context) error {
+		number, err := userNumber(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		p, err := s.Profiles.GetByID(number)
+		if err == models.ErrProfileNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		} else if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		if p.Phone != authPhone(c) {
+			return echo.NewHTTPError(http.StatusForbidden, errors.New("Profile belongs to another user"))
+		}
+		return next(c)
+	})
+}
+
+// authPhone is the phone number of the authenticated user
+func authPhone(c echo.Context) string {
+	phone, _ := c.Get(phoneKey).(string)
+	return phone
+}
+
+// SetProfileOwner binds a profile to a phone number, used for
+// profiles created before authentication existed. Profiles
+// that already belong to a phone number keep it
+// PUT /admin/profiles/:userNumber/owner {"telefone": "+5521999999999"}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 404 not found
+// 409 conflict, the phone number owns another profile
+// or the profile belongs to another phone number
+// 500 internal server error
+func (s *Server) SetProfileOwner(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var req otpRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	phone, err := auth.NormalizePhone(req.Phone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	err = s.Profiles.SetPhone(number, phone)
+	if err == models.ErrProfileNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err == models.ErrPhoneTaken || err == models.ErrProfileOwned {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	return c.JSON(http.StatusOK, p)
+}
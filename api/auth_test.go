@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+func TestSetProfileOwner(t *testing.T) {
+	s := newTestServer(t)
+	orphan := models.Profile{}
+	if err := s.Profiles.Create(&orphan); err != nil {
+		t.Fatal(err)
+	}
+	owned := login(t, s, "+5521988888888").register(moveDay(10))
+	admin := admin(t, s)
+	owner := func(p models.Profile) string {
+		return "/admin/profiles" + profilePath(p, "/owner")
+	}
+
+	// the phone of another profile
+	admin.must(http.StatusConflict, "PUT", owner(orphan), otpRequest{Phone: "+5521988888888"}, nil)
+	var p models.Profile
+	admin.must(http.StatusOK, "PUT", owner(orphan), otpRequest{Phone: "21 99999-9999"}, &p)
+	if p.Phone != "+5521999999999" {
+		t.Errorf("phone %q", p.Phone)
+	}
+	// again with the same phone, and with another one
+	admin.must(http.StatusOK, "PUT", owner(orphan), otpRequest{Phone: "+5521999999999"}, nil)
+	admin.must(http.StatusConflict, "PUT", owner(orphan), otpRequest{Phone: "+5521977777777"}, nil)
+	admin.must(http.StatusConflict, "PUT", owner(owned), otpRequest{Phone: "+5521977777777"}, nil)
+	admin.must(http.StatusNotFound, "PUT", "/admin/profiles/999/owner", otpRequest{Phone: "+5521977777777"}, nil)
+
+	// the profile is reached with the phone it was bound to
+	login(t, s, "+5521999999999").must(http.StatusOK, "GET", profilePath(orphan, ""), nil, nil)
+}
+
+// codeSender keeps the last code sent to each phone
+type codeSender struct {
+	mu    sync.Mutex
+	codes map[string]string
+}
+
+func (s *codeSender) Send(phone, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[phone] = message[strings.LastIndex(message, " ")+1:]
+	return nil
+}
+
+func (s *codeSender) code(phone string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.codes[phone]
+}
+
+func TestOTPLimits(t *testing.T) {
+	s := newTestServer(t)
+	sms := &codeSender{codes: make(map[string]string)}
+	s.SMS = sms
+	c := &client{t: t, router: s.Router()}
+	phone, other := "+5521999999999", "+5521988888888"
+
+	c.must(http.StatusAccepted, "POST", "/auth/otp", otpRequest{Phone: phone}, nil)
+	c.must(http.StatusTooManyRequests, "POST", "/auth/otp", otpRequest{Phone: phone}, nil)
+	c.must(http.StatusAccepted, "POST", "/auth/otp", otpRequest{Phone: other}, nil)
+
+	// the right code after the attempts are spent
+	for i := 0; i < otpMaxAttempts; i++ {
+		c.must(http.StatusUnauthorized, "POST", "/auth/verify", otpRequest{Phone: phone, Code: "wrong"}, nil)
+	}
+	c.must(http.StatusUnauthorized, "POST", "/auth/verify", otpRequest{Phone: phone, Code: sms.code(phone)}, nil)
+
+	var session map[string]interface{}
+	c.must(http.StatusOK, "POST", "/auth/verify", otpRequest{Phone: other, Code: sms.code(other)}, &session)
+	if session["telefone"] != other || session["token"] == "" {
+		t.Errorf("session %v", session)
+	}
+	// the code is single use
+	c.must(http.StatusUnauthorized, "POST", "/auth/verify", otpRequest{Phone: other, Code: sms.code(other)}, nil)
+}
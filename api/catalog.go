@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// ListCatalog returns the item types users can put in their boxes
+// GET /catalog
+//
+// HTTP responses:
+// 200 ok
+// 500 internal server error
+func (s *Server) ListCatalog(c echo.Context) (err error) {
+	items, err := s.Catalog.List()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// SaveCatalogItem creates or replaces an item type
+// PUT /admin/catalog/:id {"nome": "Televisão", "volume": 0.15, "peso": 12, "fragil": true}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) SaveCatalogItem(c echo.Context) (err error) {
+	var item models.CatalogItem
+	if err = c.Bind(&item); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	item.ID = c.Param("id")
+	if item.Packing == nil {
+		item.Packing = []models.PackingNeed{}
+	}
+	if err = item.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err = s.Catalog.Save(item); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, item)
+}
+
+// DeleteCatalogItem removes an item type. Inventory items
+// referencing it are then counted as unknown
+// DELETE /admin/catalog/:id
+//
+// HTTP responses:
+// 204 no content
+// 401 unauthorized
+// 404 not found
+func (s *Server) DeleteCatalogItem(c echo.Context) (err error) {
+	if err = s.Catalog.Delete(c.Param("id")); err != nil {
+		return catalogError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// InventorySummary totals volume, weight, fragile items,
+// items to disassemble and packing material of a profile
+// GET /:userNumber/summary
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 500 internal server error
+func (s *Server) InventorySummary(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	catalog, err := s.catalog()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, p.Summary(catalog))
+}
+
+func (s *Server) catalog() (models.Catalog, error) {
+	items, err := s.Catalog.List()
+	if err != nil {
+		return nil, err
+	}
+	return models.NewCatalog(items), nil
+}
+
+// prepareItem checks the catalog reference of an item,
+// naming the item after it when no type is given
+func (s *Server) prepareItem(item *models.Item) error {
+	if item.CatalogID != "" {
+		if _, err := s.Catalog.Get(item.CatalogID); err == models.ErrCatalogItemNotFound {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		} else if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		if item.Type == "" {
+			item.Type = item.CatalogID
+		}
+	}
+	if err := validateItem(item); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func catalogError(err error) error {
+	if err == models.ErrCatalogItemNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err)
+}
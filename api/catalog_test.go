@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+func TestCatalog(t *testing.T) {
+	s := newTestServer(t)
+	admin := admin(t, s)
+	c := login(t, s, "+5521999999999")
+	p := c.register(moveDay(10))
+
+	var items []models.CatalogItem
+	c.must(http.StatusOK, "GET", "/catalog", nil, &items)
+	if len(items) != len(models.DefaultCatalog()) {
+		t.Fatalf("%d catalog items", len(items))
+	}
+
+	piano := models.CatalogItem{Name: "Piano", Volume: 2, Weight: 250, Fragile: true}
+	c.must(http.StatusUnauthorized, "PUT", "/admin/catalog/piano", piano, nil)
+	admin.must(http.StatusBadRequest, "PUT", "/admin/catalog/Piano", piano, nil)
+	admin.must(http.StatusBadRequest, "PUT", "/admin/catalog/piano", models.CatalogItem{Name: "Piano", Volume: -2}, nil)
+	admin.must(http.StatusOK, "PUT", "/admin/catalog/piano", piano, nil)
+
+	// items reference the catalog, named after it when no type is given
+	box := p.Inventory[0].Boxes[0]
+	itemsPath := profilePath(p, "/rooms/sala/boxes/%s/items", box.ID)
+	var item models.Item
+	c.must(http.StatusCreated, "POST", itemsPath, map[string]interface{}{"catalogo": "piano", "quantidade": 1}, &item)
+	if item.Type != "piano" || item.CatalogID != "piano" {
+		t.Errorf("item %+v", item)
+	}
+	c.must(http.StatusBadRequest, "POST", itemsPath, map[string]interface{}{"catalogo": "harpa", "quantidade": 1}, nil)
+
+	var summary models.InventorySummary
+	c.must(http.StatusOK, "GET", profilePath(p, "/summary"), nil, &summary)
+	if summary.Load.Weight < piano.Weight || summary.Fragile != 1 {
+		t.Errorf("summary %+v", summary)
+	}
+
+	admin.must(http.StatusNoContent, "DELETE", "/admin/catalog/piano", nil, nil)
+	admin.must(http.StatusNotFound, "DELETE", "/admin/catalog/piano", nil, nil)
+}
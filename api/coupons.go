@@ -0,0 +1,180 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// couponRequest names the promo code applied to an offer
+type couponRequest struct {
+	Code string `json:"codigo"`
+}
+
+// ApplyCoupon discounts a promo code from the quoted offer,
+// replacing any coupon applied before. The use is counted
+// now and given back if the offer is quoted again or cancelled
+// POST /:userNumber/offer/coupon {"codigo": "BEMVINDO10"}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+func (s *Server) ApplyCoupon(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var req couponRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	now := time.Now()
+	if err = p.Offer.CanAccept("", now); err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	}
+	coupon, err := s.Coupons.Get(models.NormalizeCouponCode(req.Code))
+	if err == models.ErrCouponNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	offer := p.Offer
+	previous := p.Offer.Coupon
+	if previous == coupon.Code {
+		return c.JSON(http.StatusOK, p.Offer)
+	}
+	p.Offer.RemoveCoupon()
+	if err = coupon.Check(&p.Offer, now); err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	}
+	user := models.CouponUser(p.Phone)
+	if err = s.Coupons.Redeem(coupon, user); err == models.ErrCouponExhausted || err == models.ErrCouponNoUser {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	p.Offer.ApplyCoupon(coupon)
+	if err = s.saveOffer(&p, offer); err != nil {
+		s.releaseCoupon(&p, coupon.Code)
+		return
+	}
+	s.releaseCoupon(&p, previous)
+	return c.JSON(http.StatusOK, p.Offer)
+}
+
+// RemoveCoupon takes the discount out of the quoted
+// offer and gives the coupon use back
+// DELETE /:userNumber/offer/coupon
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+func (s *Server) RemoveCoupon(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	if p.Offer.CurrentStatus() != models.OfferQuoted {
+		return echo.NewHTTPError(http.StatusConflict, models.ErrInvalidTransition)
+	}
+	code := p.Offer.Coupon
+	if code == "" {
+		return c.JSON(http.StatusOK, p.Offer)
+	}
+	offer := p.Offer
+	p.Offer.RemoveCoupon()
+	if err = s.saveOffer(&p, offer); err != nil {
+		return
+	}
+	s.releaseCoupon(&p, code)
+	return c.JSON(http.StatusOK, p.Offer)
+}
+
+// releaseCoupon gives back a use of the coupon. Failing to
+// do so only costs the user a use, so it is logged
+func (s *Server) releaseCoupon(p *models.Profile, code string) {
+	if code == "" {
+		return
+	}
+	err := s.Coupons.Release(code, models.CouponUser(p.Phone))
+	if err != nil && err != models.ErrCouponNotFound {
+		log.Printf("releasing coupon %s of profile %d: %v", code, p.ID, err)
+	}
+}
+
+// ListCoupons returns every coupon with its uses
+// GET /admin/coupons
+//
+// HTTP responses:
+// 200 ok
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) ListCoupons(c echo.Context) (err error) {
+	coupons, err := s.Coupons.List()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, coupons)
+}
+
+// SaveCoupon creates or changes a coupon. Uses counted so
+// far are kept, so limits can be raised during a campaign
+// PUT /admin/coupons/:code {"tipo": "percentual", "valor": 10, "pedido_minimo": 300, "veiculos": [2, 3], "limite_total": 500, "limite_por_usuario": 1, "valido_ate": "2019-03-31T23:59:59-03:00"}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) SaveCoupon(c echo.Context) (err error) {
+	var coupon models.Coupon
+	if err = c.Bind(&coupon); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	coupon.Code = models.NormalizeCouponCode(c.Param("code"))
+	if err = coupon.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err = s.Coupons.Save(coupon); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if coupon, err = s.Coupons.Get(coupon.Code); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, coupon)
+}
+
+// DeleteCoupon ends a campaign. Offers already
+// discounted keep their discount
+// DELETE /admin/coupons/:code
+//
+// HTTP responses:
+// 204 no content
+// 401 unauthorized
+// 404 not found
+func (s *Server) DeleteCoupon(c echo.Context) (err error) {
+	err = s.Coupons.Delete(models.NormalizeCouponCode(c.Param("code")))
+	if err == models.ErrCouponNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
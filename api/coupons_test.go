@@ -0,0 +1,168 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+// failingOffers wraps the profiles to fail saving offers
+type failingOffers struct {
+	models.ProfileRepository
+	err error
+}
+
+func (r *failingOffers) SaveOffer(p *models.Profile, previous models.Offer) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.ProfileRepository.SaveOffer(p, previous)
+}
+
+// failingRedeem wraps the coupons to fail their redemption
+type failingRedeem struct {
+	models.CouponRepository
+	err error
+}
+
+func (r *failingRedeem) Redeem(c models.Coupon, user string) error {
+	return r.err
+}
+
+// uses returns the uses counted for a coupon
+func uses(t *testing.T, s *Server, code string) int {
+	t.Helper()
+	c, err := s.Coupons.Get(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Uses
+}
+
+// quotedWithCoupons quotes vehicle 2 for a new profile and
+// creates a 10% coupon of each code with a single use
+func quotedWithCoupons(t *testing.T, s *Server, codes ...string) (*client, models.Profile) {
+	t.Helper()
+	admin := admin(t, s)
+	for _, code := range codes {
+		admin.must(http.StatusOK, "PUT", "/admin/coupons/"+code, models.Coupon{Kind: models.CouponPercentage, Value: 10, MaxUses: 1}, nil)
+	}
+	c := login(t, s, "+5521999999999")
+	p := c.register(moveDay(10))
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, nil)
+	return c, p
+}
+
+func TestApplyCoupon(t *testing.T) {
+	s := newTestServer(t)
+	c, p := quotedWithCoupons(t, s, "DEZ", "OUTRO")
+
+	var offer models.Offer
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/coupon"), couponRequest{Code: "dez"}, &offer)
+	if offer.Coupon != "DEZ" || uses(t, s, "DEZ") != 1 {
+		t.Fatalf("coupon %q with %d uses", offer.Coupon, uses(t, s, "DEZ"))
+	}
+	// applying it again counts no other use
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/coupon"), couponRequest{Code: "DEZ"}, nil)
+	if uses(t, s, "DEZ") != 1 {
+		t.Errorf("%d uses after applying the coupon again", uses(t, s, "DEZ"))
+	}
+
+	// replacing the coupon gives the previous one back
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/coupon"), couponRequest{Code: "OUTRO"}, &offer)
+	if offer.Coupon != "OUTRO" || uses(t, s, "DEZ") != 0 || uses(t, s, "OUTRO") != 1 {
+		t.Errorf("coupon %q, DEZ used %d times, OUTRO %d", offer.Coupon, uses(t, s, "DEZ"), uses(t, s, "OUTRO"))
+	}
+
+	var removed models.Offer
+	c.must(http.StatusOK, "DELETE", profilePath(p, "/offer/coupon"), nil, &removed)
+	if removed.Coupon != "" || uses(t, s, "OUTRO") != 0 {
+		t.Errorf("coupon %q, OUTRO used %d times", removed.Coupon, uses(t, s, "OUTRO"))
+	}
+	c.must(http.StatusNotFound, "POST", profilePath(p, "/offer/coupon"), couponRequest{Code: "NENHUM"}, nil)
+}
+
+func TestApplyCouponGivesTheUseBackWhenNotSaved(t *testing.T) {
+	s := newTestServer(t)
+	c, p := quotedWithCoupons(t, s, "DEZ", "OUTRO")
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/coupon"), couponRequest{Code: "DEZ"}, nil)
+
+	profiles := &failingOffers{ProfileRepository: s.Profiles, err: models.ErrOfferChanged}
+	s.Profiles = profiles
+	c.must(http.StatusConflict, "POST", profilePath(p, "/offer/coupon"), couponRequest{Code: "OUTRO"}, nil)
+	if uses(t, s, "OUTRO") != 0 || uses(t, s, "DEZ") != 1 {
+		t.Errorf("OUTRO used %d times, DEZ %d after a failed save", uses(t, s, "OUTRO"), uses(t, s, "DEZ"))
+	}
+	// nor is the coupon of the stored offer given back
+	c.must(http.StatusConflict, "DELETE", profilePath(p, "/offer/coupon"), nil, nil)
+	if uses(t, s, "DEZ") != 1 {
+		t.Errorf("DEZ used %d times after a failed removal", uses(t, s, "DEZ"))
+	}
+
+	profiles.err = nil
+	var offer models.Offer
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/coupon"), couponRequest{Code: "OUTRO"}, &offer)
+	if offer.Coupon != "OUTRO" || uses(t, s, "OUTRO") != 1 || uses(t, s, "DEZ") != 0 {
+		t.Errorf("coupon %q, OUTRO used %d times, DEZ %d", offer.Coupon, uses(t, s, "OUTRO"), uses(t, s, "DEZ"))
+	}
+}
+
+func TestApplyCouponConflicts(t *testing.T) {
+	for _, err := range []error{models.ErrCouponExhausted, models.ErrCouponNoUser} {
+		t.Run(err.Error(), func(t *testing.T) {
+			s := newTestServer(t)
+			c, p := quotedWithCoupons(t, s, "DEZ")
+			s.Coupons = &failingRedeem{CouponRepository: s.Coupons, err: err}
+			c.must(http.StatusConflict, "POST", profilePath(p, "/offer/coupon"), couponRequest{Code: "DEZ"}, nil)
+			c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+			if p.Offer.Coupon != "" {
+				t.Errorf("coupon %q applied", p.Offer.Coupon)
+			}
+		})
+	}
+
+	// the single use taken by another profile
+	s := newTestServer(t)
+	c, p := quotedWithCoupons(t, s, "DEZ")
+	other := login(t, s, "+5521988888888")
+	q := other.register(moveDay(10))
+	other.must(http.StatusCreated, "POST", profilePath(q, "/2"), nil, nil)
+	other.must(http.StatusOK, "POST", profilePath(q, "/offer/coupon"), couponRequest{Code: "DEZ"}, nil)
+	c.must(http.StatusConflict, "POST", profilePath(p, "/offer/coupon"), couponRequest{Code: "DEZ"}, nil)
+}
+
+func TestApplyCouponConcurrently(t *testing.T) {
+	s := newTestServer(t)
+	admin(t, s).must(http.StatusOK, "PUT", "/admin/coupons/DEZ", models.Coupon{Kind: models.CouponPercentage, Value: 10, MaxUses: 1}, nil)
+	phones := []string{"+5521911111111", "+5521922222222", "+5521933333333", "+5521944444444"}
+	clients := make([]*client, len(phones))
+	profiles := make([]models.Profile, len(phones))
+	for i, phone := range phones {
+		clients[i] = login(t, s, phone)
+		profiles[i] = clients[i].register(moveDay(10))
+		clients[i].must(http.StatusCreated, "POST", profilePath(profiles[i], "/2"), nil, nil)
+	}
+	var wg sync.WaitGroup
+	statuses := make([]int, len(phones))
+	for i := range phones {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i] = clients[i].send("POST", profilePath(profiles[i], "/offer/coupon"), couponRequest{Code: "DEZ"}, nil).Code
+		}(i)
+	}
+	wg.Wait()
+	applied := 0
+	for _, status := range statuses {
+		if status == http.StatusOK {
+			applied++
+		} else if status != http.StatusConflict {
+			t.Errorf("status %d", status)
+		}
+	}
+	if applied != 1 || uses(t, s, "DEZ") != 1 {
+		t.Errorf("applied %d times with %d uses, want 1", applied, uses(t, s, "DEZ"))
+	}
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// ListRooms returns the inventory of a profile
+// GET /:userNumber/rooms
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+func (s *Server) ListRooms(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return inventoryError(err)
+	}
+	return c.JSON(http.StatusOK, p.Inventory)
+}
+
+// CreateRoom adds an empty room to the inventory
+// POST /:userNumber/rooms {"nome": "cozinha"}
+//
+// HTTP responses:
+// 201 created
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) CreateRoom(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var room models.Room
+	if err = c.Bind(&room); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	room.Name = strings.TrimSpace(room.Name)
+	if room.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.New("Room name is required"))
+	}
+	room.Boxes = []models.Box{}
+	err = s.editInventory(number, func() error {
+		return s.Profiles.AddRoom(number, room)
+	})
+	if err != nil {
+		return
+	}
+	return c.JSON(http.StatusCreated, room)
+}
+
+// RenameRoom changes the name of a room
+// PUT /:userNumber/rooms/:room {"nome": "quarto"}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) RenameRoom(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var room models.Room
+	if err = c.Bind(&room); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	room.Name = strings.TrimSpace(room.Name)
+	if room.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.New("Room name is required"))
+	}
+	err = s.editInventory(number, func() error {
+		return s.Profiles.RenameRoom(number, c.Param("room"), room.Name)
+	})
+	if err != nil {
+		return
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// DeleteRoom removes a room and all of its boxes
+// DELETE /:userNumber/rooms/:room
+//
+// HTTP responses:
+// 204 no content
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) DeleteRoom(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	err = s.editInventory(number, func() error {
+		return s.Profiles.RemoveRoom(number, c.Param("room"))
+	})
+	if err != nil {
+		return
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateBox appends a box, optionally already filled, to a room
+// and returns it with the ID used to address it from now on
+// POST /:userNumber/rooms/:room/boxes {"etiqueta": "livros", "items": [{"tipo": "tv", "quantidade": 1}]}
+//
+// HTTP responses:
+// 201 created
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) CreateBox(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var box models.Box
+	if err = c.Bind(&box); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	for i := range box.Items {
+		if err = s.prepareItem(&box.Items[i]); err != nil {
+			return
+		}
+	}
+	err = s.editInventory(number, func() (err error) {
+		box, err = s.Profiles.AddBox(number, c.Param("room"), strings.TrimSpace(box.Label), box.Items)
+		return
+	})
+	if err != nil {
+		return
+	}
+	return c.JSON(http.StatusCreated, box)
+}
+
+// DeleteBox removes a box from a room
+// DELETE /:userNumber/rooms/:room/boxes/:boxID
+//
+// HTTP responses:
+// 204 no content
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) DeleteBox(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	err = s.editInventory(number, func() error {
+		return s.Profiles.RemoveBox(number, c.Param("room"), c.Param("boxID"))
+	})
+	if err != nil {
+		return
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateItem puts items in a box, adding to the
+// quantity if the box already has that item type.
+// "catalogo" references an item type of the catalog,
+// which also names the item when "tipo" is omitted
+// POST /:userNumber/rooms/:room/boxes/:boxID/items {"catalogo": "tv", "quantidade": 1}
+//
+// HTTP responses:
+// 201 created
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) CreateItem(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var item models.Item
+	if err = c.Bind(&item); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err = s.prepareItem(&item); err != nil {
+		return
+	}
+	err = s.editInventory(number, func() error {
+		return s.Profiles.AddItem(number, c.Param("room"), c.Param("boxID"), item)
+	})
+	if err != nil {
+		return
+	}
+	return c.JSON(http.StatusCreated, item)
+}
+
+// UpdateItem sets the quantity of an item type inside a box
+// PUT /:userNumber/rooms/:room/boxes/:boxID/items/:item {"quantidade": 3}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) UpdateItem(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var item models.Item
+	if err = c.Bind(&item); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	item.Type = c.Param("item")
+	if err = validateItem(&item); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	err = s.editInventory(number, func() error {
+		return s.Profiles.SetItemQuantity(number, c.Param("room"), c.Param("boxID"), item.Type, item.Quantity)
+	})
+	if err != nil {
+		return
+	}
+	return c.JSON(http.StatusOK, item)
+}
+
+// DeleteItem takes an item type out of a box
+// DELETE /:userNumber/rooms/:room/boxes/:boxID/items/:item
+//
+// HTTP responses:
+// 204 no content
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) DeleteItem(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	err = s.editInventory(number, func() error {
+		return s.Profiles.RemoveItem(number, c.Param("room"), c.Param("boxID"), c.Param("item"))
+	})
+	if err != nil {
+		return
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// editInventory makes a change to the inventory of the profile.
+// An accepted offer keeps the inventory it was priced with, so
+// it must be cancelled first. The repository checks it and
+// expires an open quote along with the change, so the quote
+// cannot be accepted at the price of the inventory it had, and
+// the move must be priced again. A change that fails leaves the
+// quote as it was
+func (s *Server) editInventory(number int, edit func() error) error {
+	if err := edit(); err != nil {
+		return inventoryError(err)
+	}
+	return nil
+}
+
+func userNumber(c echo.Context) (int, error) {
+	return strconv.Atoi(c.Param("userNumber"))
+}
+
+func validateItem(item *models.Item) error {
+	item.Type = strings.TrimSpace(item.Type)
+	if item.Type == "" {
+		return errors.New("Item type is required")
+	}
+	if item.Quantity < 1 {
+		return errors.New("Item quantity must be greater than zero")
+	}
+	return nil
+}
+
+// inventoryError maps model errors to HTTP errors
+func inventoryError(err error) error {
+	switch err {
+	case models.ErrProfileNotFound, models.ErrRoomNotFound, models.ErrBoxNotFound, models.ErrItemNotFound, models.ErrStopNotFound:
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	case models.ErrRoomExists, models.ErrConflict, models.ErrStopInUse, models.ErrInventoryLocked:
+		return echo.NewHTTPError(http.StatusConflict, err)
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+func TestInventoryEditExpiresQuote(t *testing.T) {
+	s := newTestServer(t)
+	c := login(t, s, "+5521999999999")
+	p := c.register(moveDay(10))
+	var quoted models.Offer
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, &quoted)
+
+	var box models.Box
+	c.must(http.StatusCreated, "POST", profilePath(p, "/rooms/sala/boxes"), map[string]string{"etiqueta": "tv"}, &box)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if !p.Offer.Expired(time.Now()) {
+		t.Errorf("quote valid until %v after changing the inventory", p.Offer.ValidUntil)
+	}
+	c.must(http.StatusConflict, "POST", profilePath(p, "/offer/accept"),
+		acceptRequest{QuoteID: quoted.ID, bookingRequest: bookingRequest{Date: moveDay(10).Format(models.DateLayout), SlotID: "2-manha"}}, nil)
+}
+
+func TestFailedInventoryEditKeepsQuote(t *testing.T) {
+	s := newTestServer(t)
+	c := login(t, s, "+5521999999999")
+	p := c.register(moveDay(10))
+	c.must(http.StatusCreated, "POST", profilePath(p, "/rooms"), map[string]string{"nome": "cozinha"}, nil)
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, nil)
+
+	c.must(http.StatusConflict, "PUT", profilePath(p, "/rooms/cozinha"), map[string]string{"nome": "sala"}, nil)
+	c.must(http.StatusNotFound, "DELETE", profilePath(p, "/rooms/quarto"), nil, nil)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if p.Offer.CurrentStatus() != models.OfferQuoted || p.Offer.Expired(time.Now()) {
+		t.Errorf("quote %s valid until %v after failed changes", p.Offer.CurrentStatus(), p.Offer.ValidUntil)
+	}
+}
+
+func TestInventoryLockedOnceAccepted(t *testing.T) {
+	s := newTestServer(t)
+	c, p := accepted(t, s, "+5521999999999")
+	box := p.Inventory[0].Boxes[0]
+	boxPath := profilePath(p, "/rooms/sala/boxes/%s", box.ID)
+
+	c.must(http.StatusConflict, "POST", profilePath(p, "/rooms"), map[string]string{"nome": "cozinha"}, nil)
+	c.must(http.StatusConflict, "PUT", profilePath(p, "/rooms/sala"), map[string]string{"nome": "quarto"}, nil)
+	c.must(http.StatusConflict, "DELETE", profilePath(p, "/rooms/sala"), nil, nil)
+	c.must(http.StatusConflict, "POST", profilePath(p, "/rooms/sala/boxes"), map[string]string{"etiqueta": "tv"}, nil)
+	c.must(http.StatusConflict, "DELETE", boxPath, nil, nil)
+	c.must(http.StatusConflict, "POST", boxPath+"/items", map[string]interface{}{"catalogo": "tv", "quantidade": 1}, nil)
+	c.must(http.StatusConflict, "PUT", boxPath+"/items/caixa", map[string]int{"quantidade": 3}, nil)
+	c.must(http.StatusConflict, "DELETE", boxPath+"/items/caixa", nil, nil)
+
+	// and changes again once cancelled
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/cancel"), nil, nil)
+	c.must(http.StatusOK, "PUT", boxPath+"/items/caixa", map[string]int{"quantidade": 3}, nil)
+}
+
+func TestInventory(t *testing.T) {
+	s := newTestServer(t)
+	c := login(t, s, "+5521999999999")
+	p := c.register(moveDay(10))
+
+	// rooms named like other routes
+	for _, name := range []string{"rooms", "offer"} {
+		c.must(http.StatusCreated, "POST", profilePath(p, "/rooms"), map[string]string{"nome": name}, nil)
+	}
+	c.must(http.StatusConflict, "POST", profilePath(p, "/rooms"), map[string]string{"nome": "sala"}, nil)
+	c.must(http.StatusBadRequest, "POST", profilePath(p, "/rooms"), map[string]string{"nome": " "}, nil)
+	var box models.Box
+	c.must(http.StatusCreated, "POST", profilePath(p, "/rooms/offer/boxes"), map[string]interface{}{
+		"etiqueta": "tv", "items": []map[string]interface{}{{"catalogo": "tv", "quantidade": 1}},
+	}, &box)
+	if box.ID == "" || box.Label != "tv" || len(box.Items) != 1 {
+		t.Fatalf("box %+v", box)
+	}
+	for _, path := range []string{
+		profilePath(p, "/rooms/offer/boxes/%s/code", box.ID),
+		profilePath(p, "/rooms/offer/boxes/%d/code", box.Number),
+	} {
+		rec := c.send("GET", path, nil, nil)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+			t.Errorf("%s answered %d %s", path, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+	// the route before the code moved under the rooms
+	c.must(http.StatusNotFound, "GET", profilePath(p, "/sala/%s/code", p.Inventory[0].Boxes[0].ID), nil, nil)
+
+	const item = "/rooms/offer/boxes/%s/items"
+	c.must(http.StatusCreated, "POST", profilePath(p, item, box.ID), map[string]interface{}{"catalogo": "tv", "quantidade": 1}, nil)
+	c.must(http.StatusBadRequest, "POST", profilePath(p, item, box.ID), map[string]interface{}{"catalogo": "tv", "quantidade": 0}, nil)
+	c.must(http.StatusOK, "PUT", profilePath(p, item+"/tv", box.ID), map[string]int{"quantidade": 5}, nil)
+	c.must(http.StatusNotFound, "PUT", profilePath(p, item+"/sofa", box.ID), map[string]int{"quantidade": 5}, nil)
+	c.must(http.StatusOK, "PUT", profilePath(p, "/rooms/rooms"), map[string]string{"nome": "cozinha"}, nil)
+	c.must(http.StatusConflict, "PUT", profilePath(p, "/rooms/cozinha"), map[string]string{"nome": "sala"}, nil)
+
+	var rooms []models.Room
+	c.must(http.StatusOK, "GET", profilePath(p, "/rooms"), nil, &rooms)
+	if len(rooms) != 3 || rooms[1].Name != "cozinha" || rooms[2].Boxes[0].Items[0].Quantity != 5 {
+		t.Fatalf("rooms %+v", rooms)
+	}
+
+	c.must(http.StatusNoContent, "DELETE", profilePath(p, item+"/tv", box.ID), nil, nil)
+	c.must(http.StatusNoContent, "DELETE", profilePath(p, "/rooms/offer/boxes/%s", box.ID), nil, nil)
+	c.must(http.StatusNotFound, "DELETE", profilePath(p, "/rooms/offer/boxes/%s", box.ID), nil, nil)
+	c.must(http.StatusNoContent, "DELETE", profilePath(p, "/rooms/cozinha"), nil, nil)
+	c.must(http.StatusNotFound, "DELETE", profilePath(p, "/rooms/cozinha"), nil, nil)
+	c.must(http.StatusOK, "GET", profilePath(p, "/rooms"), nil, &rooms)
+	if len(rooms) != 2 || len(rooms[1].Boxes) != 0 {
+		t.Errorf("rooms %+v", rooms)
+	}
+}
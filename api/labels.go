@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MudaeH5A/4thinkbe/labels"
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// LabelSheet renders printable A4 label sheets with the QR code,
+// room, number and fragile mark of every box of the profile,
+// or of a single room when the route has one
+// GET /:userNumber/labels
+// GET /:userNumber/rooms/:room/labels
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 500 internal server error
+func (s *Server) LabelSheet(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	rooms := p.Inventory
+	if name := c.Param("room"); name != "" {
+		i, err := p.RoomIndex(name)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		rooms = []models.Room{p.Inventory[i]}
+	}
+	catalog, err := s.catalog()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	var sheet []labels.Label
+	for _, room := range rooms {
+		for _, box := range room.Boxes {
+			sheet = append(sheet, labels.Label{
+				URL:     s.boxURL(box),
+				Room:    room.Name,
+				Number:  box.Number,
+				Title:   box.Label,
+				Fragile: box.Fragile(catalog),
+			})
+		}
+	}
+	pdf, err := labels.Sheet(sheet)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	resp := c.Response()
+	resp.Header().Set("Content-Disposition", "inline; filename=etiquetas-"+strconv.Itoa(number)+".pdf")
+	return c.Blob(http.StatusOK, "application/pdf", pdf)
+}
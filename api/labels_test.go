@@ -0,0 +1,22 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestLabelSheet(t *testing.T) {
+	s := newTestServer(t)
+	c := login(t, s, "+5521999999999")
+	p := c.register(moveDay(10))
+
+	for _, path := range []string{profilePath(p, "/labels"), profilePath(p, "/rooms/sala/labels")} {
+		rec := c.send("GET", path, nil, nil)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" ||
+			!bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF")) {
+			t.Errorf("%s answered %d %s", path, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+	c.must(http.StatusNotFound, "GET", profilePath(p, "/rooms/cozinha/labels"), nil, nil)
+}
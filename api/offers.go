@@ -0,0 +1,329 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// acceptRequest accepts a quote, optionally booking its slot
+type acceptRequest struct {
+	QuoteID string `json:"cotacao"`
+	bookingRequest
+}
+
+// offerStatusRequest moves an offer to another status
+type offerStatusRequest struct {
+	Status models.OfferStatus `json:"status"`
+	Reason string             `json:"motivo"`
+}
+
+// cancelRequest tells why the offer is cancelled
+type cancelRequest struct {
+	Reason string `json:"motivo"`
+}
+
+// AcceptOffer accepts the quoted offer at the price it was
+// quoted with, as long as the quote has not expired. The move
+// must have a slot on the day it was priced for, booked before
+// or chosen in the request.
+// "cotacao" optionally names the quote being accepted
+// POST /:userNumber/offer/accept {"cotacao": "5c1d...", "data": "2019-03-01", "horario": "2-manha"}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+func (s *Server) AcceptOffer(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var req acceptRequest
+	if c.Request().ContentLength != 0 {
+		if err = c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	offer := p.Offer
+	now := time.Now()
+	if err = p.Offer.CanAccept(req.QuoteID, now); err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	}
+	var previous *models.Booking
+	reserved := req.Date != "" || req.SlotID != ""
+	if reserved && p.Offer.Date != "" && req.Date != p.Offer.Date {
+		return echo.NewHTTPError(http.StatusConflict, models.ErrQuoteOtherDate)
+	}
+	if reserved {
+		if previous, err = s.reserve(&p, req.bookingRequest); err != nil {
+			return
+		}
+	}
+	if p.Booking == nil {
+		return echo.NewHTTPError(http.StatusConflict, errors.New("Book a slot before accepting the offer"))
+	}
+	if p.Offer.Date != "" && p.Booking.Date != p.Offer.Date {
+		return echo.NewHTTPError(http.StatusConflict, models.ErrQuoteOtherDate)
+	}
+	if err = p.Offer.Accept(req.QuoteID, now); err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	}
+	if err = s.saveOffer(&p, offer); err != nil {
+		if reserved {
+			s.releaseReplaced(p.ID, p.Booking, previous)
+		}
+		return
+	}
+	s.releaseReplaced(p.ID, previous, p.Booking)
+	return c.JSON(http.StatusOK, p.Offer)
+}
+
+// CancelOffer cancels the offer and releases its slot. Paid
+// offers are refunded by the cancellation policy of the tariff
+// they were priced with, depending on how long before the move
+// they are cancelled. A new offer can be quoted afterwards
+// POST /:userNumber/offer/cancel {"motivo": "Mudei de data"}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+func (s *Server) CancelOffer(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var req cancelRequest
+	if c.Request().ContentLength != 0 {
+		if err = c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	if err = s.cancelOffer(&p, req.Reason); err != nil {
+		return
+	}
+	return c.JSON(http.StatusOK, p.Offer)
+}
+
+// CancellationTerms tells what cancelling the offer now
+// would refund, without cancelling it
+// GET /:userNumber/offer/cancellation
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+func (s *Server) CancellationTerms(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	if !p.Offer.CanGo(models.OfferCancelled) {
+		return echo.NewHTTPError(http.StatusConflict, models.ErrInvalidTransition)
+	}
+	cancellation, err := s.cancellation(&p, "", time.Now())
+	if err != nil {
+		return
+	}
+	return c.JSON(http.StatusOK, cancellation)
+}
+
+// RefundOffer makes the refund of a cancelled offer
+// the payment provider failed to make on cancellation
+// POST /admin/profiles/:userNumber/offer/refund
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 404 not found
+// 409 conflict
+// 500 internal server error
+// 502 bad gateway
+func (s *Server) RefundOffer(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	cancellation := p.Offer.Cancellation
+	if cancellation == nil || !cancellation.RefundDue() {
+		return echo.NewHTTPError(http.StatusConflict, errors.New("Offer has no refund to make"))
+	}
+	if err = s.refund(&p); err != nil {
+		return
+	}
+	return c.JSON(http.StatusOK, p.Offer)
+}
+
+// SetOfferStatus moves the offer of a profile along its
+// lifecycle, to mark it paid or completed. Offers are only
+// quoted by choosing a vehicle and accepted by their owner
+// PUT /admin/profiles/:userNumber/offer/status {"status": "cancelled", "motivo": "Caminhão indisponível"}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 404 not found
+// 409 conflict
+// 500 internal server error
+func (s *Server) SetOfferStatus(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var req offerStatusRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	switch req.Status {
+	case models.OfferPaid, models.OfferCompleted, models.OfferCancelled:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, errors.New("status must be paid, completed or cancelled"))
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	if req.Status == models.OfferCancelled {
+		err = s.cancelOffer(&p, req.Reason)
+	} else {
+		err = s.moveOffer(&p, req.Status)
+	}
+	if err != nil {
+		return
+	}
+	return c.JSON(http.StatusOK, p.Offer)
+}
+
+// cancelOffer cancels the offer, recording what the policy
+// refunds, and asks the payment provider for the refund.
+// A refund the provider refuses is recorded as failed on the
+// cancellation, shown in the answer, and left to be made again
+func (s *Server) cancelOffer(p *models.Profile, reason string) error {
+	if !p.Offer.CanGo(models.OfferCancelled) {
+		return echo.NewHTTPError(http.StatusConflict, models.ErrInvalidTransition)
+	}
+	cancellation, err := s.cancellation(p, reason, time.Now())
+	if err != nil {
+		return err
+	}
+	p.Offer.Cancellation = &cancellation
+	if err = s.moveOffer(p, models.OfferCancelled); err != nil {
+		return err
+	}
+	if p.Offer.Cancellation.RefundDue() {
+		if err = s.refund(p); err != nil {
+			log.Printf("refunding offer %s of profile %d: %v", p.Offer.ID, p.ID, err)
+		}
+	}
+	return nil
+}
+
+// cancellation applies the policy of the tariff the offer was
+// priced with. Offers priced before tariffs are fully refunded
+func (s *Server) cancellation(p *models.Profile, reason string, at time.Time) (c models.Cancellation, err error) {
+	tariff, err := s.Tariffs.Get(p.Offer.TariffVersion)
+	if err != nil && err != models.ErrTariffNotFound {
+		return c, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return p.Cancellation(tariff.Cancellation, reason, at), nil
+}
+
+// refund gives back the refund of the cancelled offer through
+// the charge that paid it. The refund is saved as requested
+// before asking the provider, so a refund made at the same time
+// finds it changed and is refused instead of made twice. Then the
+// provider refund, or why the provider failed to make it, is
+// recorded
+func (s *Server) refund(p *models.Profile) error {
+	cancellation := p.Offer.Cancellation
+	if p.Offer.Payment == "" {
+		return echo.NewHTTPError(http.StatusConflict, errors.New("Offer was not paid through the payment provider"))
+	}
+	previous := p.Offer
+	cancellation.RefundStatus = models.RefundRequested
+	cancellation.RefundError = ""
+	if err := s.saveOffer(p, previous); err != nil {
+		return err
+	}
+	r, errR := s.Gateway.Refund(p.Offer.Payment, cancellation.Refund)
+	previous = p.Offer
+	if errR != nil {
+		cancellation.RefundStatus = models.RefundFailed
+		cancellation.RefundError = errR.Error()
+	} else {
+		cancellation.RefundID = r.ID
+	}
+	if err := s.saveOffer(p, previous); err != nil {
+		return err
+	}
+	if errR != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, errR)
+	}
+	return nil
+}
+
+// moveOffer changes the offer status and saves the profile,
+// releasing the slot and the coupon of cancelled offers
+func (s *Server) moveOffer(p *models.Profile, to models.OfferStatus) error {
+	previous := p.Offer
+	if err := p.Offer.Transition(to, time.Now()); err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	}
+	booking := p.Booking
+	if to == models.OfferCancelled {
+		p.Booking = nil
+	}
+	if err := s.saveOffer(p, previous); err != nil {
+		return err
+	}
+	if to == models.OfferCancelled {
+		if booking != nil {
+			s.release(p.ID, booking)
+		}
+		s.releaseCoupon(p, p.Offer.Coupon)
+	}
+	return nil
+}
+
+// saveOffer saves the changes made to the offer since it was
+// read as previous, refusing them when it changed meanwhile
+func (s *Server) saveOffer(p *models.Profile, previous models.Offer) error {
+	err := s.Profiles.SaveOffer(p, previous)
+	if err == models.ErrOfferChanged || err == models.ErrStopInUse {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	} else if err == models.ErrProfileNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return nil
+}
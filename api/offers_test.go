@@ -0,0 +1,131 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/MudaeH5A/4thinkbe/payments"
+)
+
+// refundGateway wraps the fake provider to count refunds, fail
+// them, and run something while the provider is refunding
+type refundGateway struct {
+	payments.PaymentProvider
+	refunds int
+	err     error
+	during  func()
+}
+
+func (g *refundGateway) Refund(chargeID string, amount float64) (payments.Refund, error) {
+	if during := g.during; during != nil {
+		g.during = nil
+		during()
+	}
+	if g.err != nil {
+		return payments.Refund{}, g.err
+	}
+	g.refunds++
+	return g.PaymentProvider.Refund(chargeID, amount)
+}
+
+// accepted registers a profile moving in ten days and
+// accepts a quote of vehicle 2 in the morning slot
+func accepted(t *testing.T, s *Server, phone string) (*client, models.Profile) {
+	t.Helper()
+	c := login(t, s, phone)
+	p := c.register(moveDay(10))
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, nil)
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/accept"),
+		bookingRequest{Date: moveDay(10).Format(models.DateLayout), SlotID: "2-manha"}, nil)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	return c, p
+}
+
+// paid pays the accepted offer by Pix through the webhook
+func paid(t *testing.T, s *Server, phone string) (*client, models.Profile, models.Payment) {
+	t.Helper()
+	c, p := accepted(t, s, phone)
+	var payment models.Payment
+	c.must(http.StatusCreated, "POST", profilePath(p, "/offer/payment"), paymentRequest{Method: payments.Pix}, &payment)
+	webhook(t, s, "evt_paid_"+payment.ID, payments.ChargePaid, payment.ID, payment.Amount, http.StatusOK)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if p.Offer.CurrentStatus() != models.OfferPaid {
+		t.Fatalf("offer %s after the payment", p.Offer.CurrentStatus())
+	}
+	return c, p, payment
+}
+
+// webhook posts an event of the fake provider, signed
+// with the test secret, expecting the status
+func webhook(t *testing.T, s *Server, id string, event payments.EventType, charge string, amount float64, status int) {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"id": id, "tipo": event, "cobranca": charge, "valor": amount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := hmac.New(sha256.New, []byte(testWebhookSecret))
+	m.Write(body)
+	header := http.Header{payments.SignatureHeader: {"sha256=" + hex.EncodeToString(m.Sum(nil))}}
+	c := &client{t: t, router: s.Router()}
+	if rec := c.send("POST", "/payments/webhook", body, header); rec.Code != status {
+		t.Fatalf("webhook %s of %s = %d, want %d", event, charge, rec.Code, status)
+	}
+}
+
+func TestRefundIsMadeOnce(t *testing.T) {
+	s := newTestServer(t)
+	gateway := &refundGateway{PaymentProvider: s.Gateway}
+	s.Gateway = gateway
+	c, p, _ := paid(t, s, "+5521999999999")
+
+	// an admin refund while the cancellation is refunding
+	// finds the refund already requested
+	admin := admin(t, s)
+	gateway.during = func() {
+		admin.must(http.StatusConflict, "POST", "/admin/profiles"+profilePath(p, "/offer/refund"), nil, nil)
+	}
+	var offer models.Offer
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/cancel"), nil, &offer)
+	if gateway.refunds != 1 {
+		t.Fatalf("%d refunds, want 1", gateway.refunds)
+	}
+	cancellation := offer.Cancellation
+	if cancellation.RefundStatus != models.RefundRequested || cancellation.RefundID == "" || cancellation.Refund != p.Offer.TotalValue {
+		t.Errorf("cancellation %+v", cancellation)
+	}
+	admin.must(http.StatusConflict, "POST", "/admin/profiles"+profilePath(p, "/offer/refund"), nil, nil)
+	if gateway.refunds != 1 {
+		t.Errorf("%d refunds after refunding again, want 1", gateway.refunds)
+	}
+}
+
+func TestFailedRefundIsMadeAgain(t *testing.T) {
+	s := newTestServer(t)
+	gateway := &refundGateway{PaymentProvider: s.Gateway, err: errors.New("provider unavailable")}
+	s.Gateway = gateway
+	c, p, _ := paid(t, s, "+5521999999999")
+
+	var offer models.Offer
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/cancel"), nil, &offer)
+	if cancellation := offer.Cancellation; cancellation.RefundStatus != models.RefundFailed || cancellation.RefundError != "provider unavailable" {
+		t.Fatalf("cancellation %+v", cancellation)
+	}
+	admin := admin(t, s)
+	admin.must(http.StatusBadGateway, "POST", "/admin/profiles"+profilePath(p, "/offer/refund"), nil, nil)
+
+	gateway.err = nil
+	var refunded models.Offer
+	admin.must(http.StatusOK, "POST", "/admin/profiles"+profilePath(p, "/offer/refund"), nil, &refunded)
+	if cancellation := refunded.Cancellation; cancellation.RefundStatus != models.RefundRequested || cancellation.RefundID == "" || cancellation.RefundError != "" {
+		t.Errorf("cancellation %+v", cancellation)
+	}
+	if gateway.refunds != 1 {
+		t.Errorf("%d refunds, want 1", gateway.refunds)
+	}
+}
@@ -0,0 +1,445 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/MudaeH5A/4thinkbe/payments"
+	"github.com/labstack/echo"
+)
+
+// paymentRequest chooses how an accepted offer is paid
+type paymentRequest struct {
+	Method    payments.Method `json:"metodo"`
+	CardToken string          `json:"cartao"`
+}
+
+// CreatePayment charges the price of the accepted offer, by
+// card or Pix. A pending charge of the same method is returned
+// again instead of charging twice. The offer becomes paid when
+// the provider confirms it through the webhook
+// POST /:userNumber/offer/payment {"metodo": "pix"}
+//
+// HTTP responses:
+// 200 ok
+// 201 created
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+// 502 bad gateway
+func (s *Server) CreatePayment(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var req paymentRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if req.Method != payments.Card && req.Method != payments.Pix {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.New("metodo must be cartao or pix"))
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	payment, created, err := s.pay(&p, req)
+	if err != nil {
+		return
+	}
+	if !created {
+		return c.JSON(http.StatusOK, payment)
+	}
+	return c.JSON(http.StatusCreated, payment)
+}
+
+// pay returns the pending charge of the accepted offer by
+// the method, creating it at the provider when there is none
+func (s *Server) pay(p *models.Profile, req paymentRequest) (payment models.Payment, created bool, err error) {
+	if p.Offer.CurrentStatus() != models.OfferAccepted {
+		return payment, false, echo.NewHTTPError(http.StatusConflict, errors.New("Only accepted offers can be paid"))
+	}
+	existing, err := s.Payments.ForQuote(p.Offer.ID)
+	if err != nil {
+		return payment, false, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	now := time.Now()
+	for _, payment = range existing {
+		if payment.Status == models.PaymentPending && payment.Method == string(req.Method) && !payment.Static &&
+			payment.Amount == p.Offer.TotalValue && (payment.ExpiresAt == nil || now.Before(*payment.ExpiresAt)) {
+			return payment, false, nil
+		}
+	}
+	charge := payments.ChargeRequest{
+		Reference:   p.Offer.ID,
+		Amount:      p.Offer.TotalValue,
+		Description: "Mudança " + p.Offer.Date,
+		Payer:       p.Phone,
+		CardToken:   req.CardToken,
+	}
+	var c payments.Charge
+	if req.Method == payments.Pix {
+		c, err = s.Gateway.CreatePixCharge(charge)
+	} else {
+		c, err = s.Gateway.CreateCharge(charge)
+	}
+	if err != nil {
+		return payment, false, echo.NewHTTPError(http.StatusBadGateway, err)
+	}
+	payment = models.Payment{
+		ID:          c.ID,
+		Profile:     p.ID,
+		QuoteID:     p.Offer.ID,
+		Method:      string(c.Method),
+		Amount:      c.Amount,
+		Status:      models.PaymentPending,
+		CheckoutURL: c.CheckoutURL,
+		PixCode:     c.PixCode,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if !c.ExpiresAt.IsZero() {
+		payment.ExpiresAt = &c.ExpiresAt
+	}
+	if err = s.Payments.Create(payment); err != nil {
+		return payment, false, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return payment, true, nil
+}
+
+// ListPayments returns the charges of the current offer
+// GET /:userNumber/offer/payments
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 500 internal server error
+func (s *Server) ListPayments(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	list, err := s.Payments.ForQuote(p.Offer.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+// PaymentWebhook receives the callbacks of the payment
+// provider, signed by it. A paid charge moves its offer to
+// paid, or is refunded when the offer was cancelled or quoted
+// again meanwhile, and a refunded charge cancels the offer it
+// paid. Providers retry until they get a 2xx answer and may
+// deliver an event twice or late, so events already applied or
+// that would take the payment back are acknowledged without
+// applying them
+// POST /payments/webhook
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 404 not found
+// 409 conflict
+// 500 internal server error
+func (s *Server) PaymentWebhook(c echo.Context) (err error) {
+	body, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	event, err := s.Gateway.Webhook(body, c.Request().Header)
+	if err == payments.ErrInvalidSignature {
+		return echo.NewHTTPError(http.StatusUnauthorized, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	// events are told apart by their ID, so one without
+	// it would be taken for every other one without it
+	if event.ID == "" || event.ChargeID == "" || event.Type == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.New("Event must have an id, a type and a charge"))
+	}
+	payment, err := s.Payments.Get(event.ChargeID)
+	if err == models.ErrPaymentNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	var status models.PaymentStatus
+	switch event.Type {
+	case payments.ChargePaid:
+		status = models.PaymentPaid
+	case payments.ChargeFailed:
+		status = models.PaymentFailed
+	case payments.ChargeRefunded:
+		status = models.PaymentRefunded
+	default:
+		// events of no interest are acknowledged
+		return c.NoContent(http.StatusOK)
+	}
+	if payment.Applied(event.ID) || !payment.Status.Precedes(status) {
+		return c.NoContent(http.StatusOK)
+	}
+	kept := true
+	switch status {
+	case models.PaymentPaid:
+		if !payment.Charged(event.Amount) {
+			log.Printf("payment %s of %.2f reported paid with %.2f", payment.ID, payment.Amount, event.Amount)
+			return echo.NewHTTPError(http.StatusBadRequest, errors.New("Paid amount does not match the charge"))
+		}
+		if kept, err = s.payOffer(payment); err != nil {
+			return webhookError(err)
+		}
+		if !kept {
+			if err = s.refundDue(payment); err != nil {
+				return webhookError(err)
+			}
+		}
+	case models.PaymentRefunded:
+		if err = s.refundedOffer(payment, event); err != nil {
+			return webhookError(err)
+		}
+	}
+	applied, err := s.Payments.Apply(payment.ID, event.ID, status, time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	// refunded once the payment is recorded, so a retried
+	// callback does not refund it twice. A refund that fails
+	// stays due for the admin refund routes
+	if applied && !kept {
+		if err = s.refundPayment(payment); err != nil {
+			log.Printf("refunding payment %s of profile %d: %v", payment.ID, payment.Profile, err)
+		}
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// webhookError answers an event that could not be applied
+// with 500, unless the error already has its HTTP status
+func webhookError(err error) error {
+	if _, ok := err.(*echo.HTTPError); ok {
+		return err
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err)
+}
+
+// payOffer moves the offer of a paid charge to paid, reading it
+// again when it changed meanwhile. It is run again when a callback
+// is retried, so an offer already paid by the charge is left as it
+// is. It tells whether the offer took the money, which a quote
+// replaced or cancelled before the charge was paid did not
+func (s *Server) payOffer(payment models.Payment) (bool, error) {
+	for {
+		p, err := s.Profiles.GetByID(payment.Profile)
+		if err != nil {
+			return false, err
+		}
+		if p.Offer.Payment == payment.ID {
+			// a cancelled offer only has the charge of a payment
+			// that arrived after it, recorded to be refunded
+			return p.Offer.CurrentStatus() != models.OfferCancelled, nil
+		}
+		if p.Offer.ID != payment.QuoteID || p.Offer.CurrentStatus() != models.OfferAccepted {
+			return false, nil
+		}
+		previous := p.Offer
+		if err = p.Offer.Transition(models.OfferPaid, time.Now()); err != nil {
+			return false, err
+		}
+		p.Offer.Payment = payment.ID
+		if err = s.Profiles.SaveOffer(&p, previous); err != models.ErrOfferChanged {
+			return err == nil, err
+		}
+	}
+}
+
+// refundDue records the refund of a charge paid for an offer that
+// did not take it, before the payment is applied. An offer cancelled
+// before it was paid records it on its cancellation, as paid and
+// given back in full, and other charges on the payment itself
+func (s *Server) refundDue(payment models.Payment) error {
+	for {
+		p, err := s.Profiles.GetByID(payment.Profile)
+		if err != nil {
+			return err
+		}
+		offer := p.Offer
+		if offer.ID == payment.QuoteID && offer.CurrentStatus() == models.OfferCancelled && offer.Cancellation != nil {
+			if offer.Payment == payment.ID {
+				return nil
+			}
+			if offer.Payment == "" {
+				p.Offer.Payment = payment.ID
+				p.Offer.Cancellation.Paid = payment.Amount
+				p.Offer.Cancellation.Refund = payment.Amount
+				p.Offer.Cancellation.RefundStatus = models.RefundPending
+				if err = s.Profiles.SaveOffer(&p, offer); err != models.ErrOfferChanged {
+					return err
+				}
+				continue
+			}
+		}
+		err = s.Payments.SetRefund(payment.ID, []models.RefundStatus{""}, models.RefundPending, "", "")
+		if err == models.ErrRefundChanged {
+			// recorded by a callback retried
+			return nil
+		}
+		return err
+	}
+}
+
+// refundPayment makes the refund recorded by refundDue
+func (s *Server) refundPayment(payment models.Payment) error {
+	p, err := s.Profiles.GetByID(payment.Profile)
+	if err != nil {
+		return err
+	}
+	if cancellation := p.Offer.Cancellation; p.Offer.Payment == payment.ID && cancellation != nil {
+		if !cancellation.RefundDue() {
+			return nil
+		}
+		return s.refund(&p)
+	}
+	return s.refundCharge(payment)
+}
+
+// refundCharge gives back a charge in full. Like the refunds
+// of cancelled offers, the refund is saved as requested before
+// asking the provider, so it is never made twice
+func (s *Server) refundCharge(payment models.Payment) error {
+	err := s.Payments.SetRefund(payment.ID, []models.RefundStatus{models.RefundPending, models.RefundFailed}, models.RefundRequested, "", "")
+	if err == models.ErrRefundChanged {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	r, errR := s.Gateway.Refund(payment.ID, payment.Amount)
+	requested := []models.RefundStatus{models.RefundRequested}
+	if errR != nil {
+		err = s.Payments.SetRefund(payment.ID, requested, models.RefundFailed, "", errR.Error())
+	} else {
+		err = s.Payments.SetRefund(payment.ID, requested, models.RefundRequested, r.ID, "")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if errR != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, errR)
+	}
+	return nil
+}
+
+// refundedOffer applies a refund made at the provider to the offer
+// the charge paid: the refund of a cancelled offer is done, and a
+// paid offer is cancelled, keeping what was not refunded. The refund
+// of a charge no offer took is done on the payment. The event
+// stands for the refund, which has no ID of its own
+func (s *Server) refundedOffer(payment models.Payment, event payments.Event) error {
+	p, err := s.Profiles.GetByID(payment.Profile)
+	if err != nil {
+		return err
+	}
+	if p.Offer.Payment != payment.ID {
+		if payment.RefundStatus == "" || payment.RefundStatus == models.RefundDone {
+			return nil
+		}
+		refundID := payment.RefundID
+		if refundID == "" {
+			refundID = event.ID
+		}
+		due := []models.RefundStatus{models.RefundPending, models.RefundRequested, models.RefundFailed}
+		if err = s.Payments.SetRefund(payment.ID, due, models.RefundDone, refundID, ""); err == models.ErrRefundChanged {
+			return nil
+		}
+		return err
+	}
+	if cancellation := p.Offer.Cancellation; p.Offer.CurrentStatus() == models.OfferCancelled && cancellation != nil {
+		if cancellation.RefundStatus == models.RefundDone {
+			return nil
+		}
+		previous := p.Offer
+		cancellation.RefundStatus = models.RefundDone
+		cancellation.RefundError = ""
+		if cancellation.RefundID == "" {
+			cancellation.RefundID = event.ID
+		}
+		return s.saveOffer(&p, previous)
+	}
+	if p.Offer.CurrentStatus() != models.OfferPaid {
+		return nil
+	}
+	cancellation, err := s.cancellation(&p, "Reembolsado pelo provedor de pagamento", time.Now())
+	if err != nil {
+		return err
+	}
+	// the fee is what the provider kept, not what the policy says
+	cancellation.Refund = event.Amount
+	cancellation.Fee = 0
+	if cancellation.Paid > 0 {
+		cancellation.Fee = math.Round(10000*(cancellation.Paid-event.Amount)/cancellation.Paid) / 100
+	}
+	cancellation.RefundStatus = models.RefundDone
+	cancellation.RefundID = event.ID
+	p.Offer.Cancellation = &cancellation
+	return s.moveOffer(&p, models.OfferCancelled)
+}
+
+// ListRefundsDue returns the charges paid for offers that did not
+// take them and are still to be refunded, because the provider
+// failed or the refund was interrupted. Refunds of cancelled offers
+// are on their cancellation instead
+// GET /admin/payments/refunds
+//
+// HTTP responses:
+// 200 ok
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) ListRefundsDue(c echo.Context) (err error) {
+	list, err := s.Payments.RefundsDue()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+// RefundPayment makes again the refund of a charge listed
+// by ListRefundsDue
+// POST /admin/payments/:payment/refund
+//
+// HTTP responses:
+// 200 ok
+// 401 unauthorized
+// 404 not found
+// 409 conflict
+// 500 internal server error
+// 502 bad gateway
+func (s *Server) RefundPayment(c echo.Context) (err error) {
+	payment, err := s.Payments.Get(c.Param("payment"))
+	if err == models.ErrPaymentNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if !payment.RefundDue() {
+		return echo.NewHTTPError(http.StatusConflict, errors.New("Payment has no refund to make"))
+	}
+	if err = s.refundCharge(payment); err != nil {
+		return
+	}
+	if payment, err = s.Payments.Get(payment.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, payment)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/MudaeH5A/4thinkbe/payments"
+)
+
+func TestWebhookPaysOnce(t *testing.T) {
+	s := newTestServer(t)
+	c, p, payment := paid(t, s, "+5521999999999")
+
+	// delivered again, and a failure reported late
+	webhook(t, s, "evt_paid_"+payment.ID, payments.ChargePaid, payment.ID, payment.Amount, http.StatusOK)
+	webhook(t, s, "evt_failed", payments.ChargeFailed, payment.ID, payment.Amount, http.StatusOK)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if p.Offer.CurrentStatus() != models.OfferPaid || p.Offer.Payment != payment.ID {
+		t.Errorf("offer %s paid by %q", p.Offer.CurrentStatus(), p.Offer.Payment)
+	}
+	stored, err := s.Payments.Get(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.PaymentPaid {
+		t.Errorf("payment %s", stored.Status)
+	}
+}
+
+func TestWebhookRefusesOtherAmount(t *testing.T) {
+	s := newTestServer(t)
+	c, p := accepted(t, s, "+5521999999999")
+	var payment models.Payment
+	c.must(http.StatusCreated, "POST", profilePath(p, "/offer/payment"), paymentRequest{Method: payments.Pix}, &payment)
+
+	webhook(t, s, "evt_paid", payments.ChargePaid, payment.ID, payment.Amount-1, http.StatusBadRequest)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if p.Offer.CurrentStatus() != models.OfferAccepted {
+		t.Errorf("offer %s", p.Offer.CurrentStatus())
+	}
+}
+
+func TestWebhookRefusesIncompleteEvents(t *testing.T) {
+	s := newTestServer(t)
+	c, p := accepted(t, s, "+5521999999999")
+	var payment models.Payment
+	c.must(http.StatusCreated, "POST", profilePath(p, "/offer/payment"), paymentRequest{Method: payments.Pix}, &payment)
+
+	webhook(t, s, "", payments.ChargePaid, payment.ID, payment.Amount, http.StatusBadRequest)
+	webhook(t, s, "evt_paid", payments.ChargePaid, "", payment.Amount, http.StatusBadRequest)
+	webhook(t, s, "evt_paid", "", payment.ID, payment.Amount, http.StatusBadRequest)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if p.Offer.CurrentStatus() != models.OfferAccepted {
+		t.Errorf("offer %s", p.Offer.CurrentStatus())
+	}
+	webhook(t, s, "evt_paid", payments.ChargePaid, payment.ID, payment.Amount, http.StatusOK)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if p.Offer.CurrentStatus() != models.OfferPaid {
+		t.Errorf("offer %s after the complete event", p.Offer.CurrentStatus())
+	}
+}
+
+func TestWebhookRefundsReplacedQuote(t *testing.T) {
+	s := newTestServer(t)
+	gateway := &refundGateway{PaymentProvider: s.Gateway, err: errors.New("provider unavailable")}
+	s.Gateway = gateway
+	c, p := accepted(t, s, "+5521999999999")
+	var payment models.Payment
+	c.must(http.StatusCreated, "POST", profilePath(p, "/offer/payment"), paymentRequest{Method: payments.Pix}, &payment)
+
+	// cancelled and quoted again before the money arrives
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/cancel"), nil, nil)
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, nil)
+	webhook(t, s, "evt_paid", payments.ChargePaid, payment.ID, payment.Amount, http.StatusOK)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if p.Offer.CurrentStatus() != models.OfferQuoted || p.Offer.Payment != "" {
+		t.Fatalf("offer %s paid by %q", p.Offer.CurrentStatus(), p.Offer.Payment)
+	}
+
+	// the refund failed and is listed to be made again
+	admin := admin(t, s)
+	var due []models.Payment
+	admin.must(http.StatusOK, "GET", "/admin/payments/refunds", nil, &due)
+	if len(due) != 1 || due[0].ID != payment.ID || due[0].RefundStatus != models.RefundFailed || due[0].RefundError != "provider unavailable" {
+		t.Fatalf("refunds due %+v", due)
+	}
+	admin.must(http.StatusBadGateway, "POST", "/admin/payments/"+payment.ID+"/refund", nil, nil)
+
+	gateway.err = nil
+	var refunded models.Payment
+	admin.must(http.StatusOK, "POST", "/admin/payments/"+payment.ID+"/refund", nil, &refunded)
+	if refunded.RefundStatus != models.RefundRequested || refunded.RefundID == "" || refunded.RefundError != "" {
+		t.Errorf("payment %+v", refunded)
+	}
+	if gateway.refunds != 1 {
+		t.Errorf("%d refunds, want 1", gateway.refunds)
+	}
+	admin.must(http.StatusConflict, "POST", "/admin/payments/"+payment.ID+"/refund", nil, nil)
+	admin.must(http.StatusOK, "GET", "/admin/payments/refunds", nil, &due)
+	if len(due) != 0 {
+		t.Errorf("refunds due %+v", due)
+	}
+
+	// confirmed by the provider
+	webhook(t, s, "evt_refunded", payments.ChargeRefunded, payment.ID, payment.Amount, http.StatusOK)
+	stored, err := s.Payments.Get(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RefundStatus != models.RefundDone {
+		t.Errorf("refund %s", stored.RefundStatus)
+	}
+}
+
+func TestWebhookRefundsCancelledOffer(t *testing.T) {
+	s := newTestServer(t)
+	gateway := &refundGateway{PaymentProvider: s.Gateway}
+	s.Gateway = gateway
+	c, p := accepted(t, s, "+5521999999999")
+	var payment models.Payment
+	c.must(http.StatusCreated, "POST", profilePath(p, "/offer/payment"), paymentRequest{Method: payments.Pix}, &payment)
+
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/cancel"), nil, nil)
+	webhook(t, s, "evt_paid", payments.ChargePaid, payment.ID, payment.Amount, http.StatusOK)
+	webhook(t, s, "evt_paid", payments.ChargePaid, payment.ID, payment.Amount, http.StatusOK)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	cancellation := p.Offer.Cancellation
+	if p.Offer.CurrentStatus() != models.OfferCancelled || p.Offer.Payment != payment.ID || cancellation == nil {
+		t.Fatalf("offer %s paid by %q", p.Offer.CurrentStatus(), p.Offer.Payment)
+	}
+	if cancellation.Paid != payment.Amount || cancellation.Refund != payment.Amount ||
+		cancellation.RefundStatus != models.RefundRequested || cancellation.RefundID == "" {
+		t.Errorf("cancellation %+v", cancellation)
+	}
+	if gateway.refunds != 1 {
+		t.Errorf("%d refunds, want 1", gateway.refunds)
+	}
+}
+
+func TestWebhookRefundCancelsPaidOffer(t *testing.T) {
+	s := newTestServer(t)
+	c, p, payment := paid(t, s, "+5521999999999")
+
+	webhook(t, s, "evt_refunded", payments.ChargeRefunded, payment.ID, payment.Amount, http.StatusOK)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	cancellation := p.Offer.Cancellation
+	if p.Offer.CurrentStatus() != models.OfferCancelled || cancellation == nil {
+		t.Fatalf("offer %s", p.Offer.CurrentStatus())
+	}
+	if cancellation.Refund != payment.Amount || cancellation.RefundStatus != models.RefundDone {
+		t.Errorf("cancellation %+v", cancellation)
+	}
+}
+
+func TestStaticPixIsNotReused(t *testing.T) {
+	s := newTestServer(t)
+	c, p := accepted(t, s, "+5521999999999")
+
+	var static, dynamic map[string]interface{}
+	c.must(http.StatusOK, "GET", profilePath(p, "/offer/pix?tipo=estatico"), nil, &static)
+	if static["estatico"] != true {
+		t.Errorf("static payment %v", static)
+	}
+	if _, ok := static["expira_em"]; ok {
+		t.Errorf("static payment expires at %v", static["expira_em"])
+	}
+	c.must(http.StatusCreated, "POST", profilePath(p, "/offer/payment"), paymentRequest{Method: payments.Pix}, &dynamic)
+	if dynamic["id"] == static["id"] || dynamic["estatico"] != nil || dynamic["expira_em"] == nil {
+		t.Errorf("dynamic payment %v, static %v", dynamic, static)
+	}
+}
+
+// racingPayments runs something before the first
+// lookup of a payment answers it was not found
+type racingPayments struct {
+	models.PaymentRepository
+	during func()
+}
+
+func (r *racingPayments) Get(id string) (models.Payment, error) {
+	if during := r.during; during != nil {
+		r.during = nil
+		during()
+		return models.Payment{}, models.ErrPaymentNotFound
+	}
+	return r.PaymentRepository.Get(id)
+}
+
+func TestStaticPixAtTheSameTime(t *testing.T) {
+	s := newTestServer(t)
+	c, p := accepted(t, s, "+5521999999999")
+	repository := &racingPayments{PaymentRepository: s.Payments}
+	s.Payments = repository
+
+	var first, second models.Payment
+	repository.during = func() {
+		c.must(http.StatusOK, "GET", profilePath(p, "/offer/pix?tipo=estatico"), nil, &first)
+	}
+	c.must(http.StatusOK, "GET", profilePath(p, "/offer/pix?tipo=estatico"), nil, &second)
+	if first.ID != p.Offer.ID || second.ID != first.ID || second.PixCode != first.PixCode {
+		t.Errorf("payments %+v and %+v", first, second)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/MudaeH5A/4thinkbe/payments"
+	"github.com/MudaeH5A/4thinkbe/pix"
+	"github.com/labstack/echo"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// PixCode returns the Pix copy and paste code paying the
+// accepted offer (pix_copia_e_cola). The dynamic code is a
+// charge of the payment provider, the static one is paid to
+// PIX_KEY with the quote ID as transaction ID, which the
+// provider webhook reports as the charge
+// GET /:userNumber/offer/pix?tipo=estatico
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+// 502 bad gateway
+// 503 service unavailable
+func (s *Server) PixCode(c echo.Context) (err error) {
+	payment, err := s.pixPayment(c)
+	if err != nil {
+		return
+	}
+	return c.JSON(http.StatusOK, payment)
+}
+
+// PixQRCode renders the Pix code of the accepted offer
+// as a QR code read by the bank apps
+// GET /:userNumber/offer/pix.png?tipo=estatico
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+// 502 bad gateway
+// 503 service unavailable
+func (s *Server) PixQRCode(c echo.Context) (err error) {
+	payment, err := s.pixPayment(c)
+	if err != nil {
+		return
+	}
+	png, err := qrcode.Encode(payment.PixCode, qrcode.Medium, 512)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	resp := c.Response()
+	resp.Header().Set("Content-Type", "image/png")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(png)))
+	_, err = resp.Write(png)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return
+}
+
+// pixPayment returns the Pix payment of the accepted offer
+// of the kind asked by "tipo", dinamico by default
+func (s *Server) pixPayment(c echo.Context) (payment models.Payment, err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return payment, echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return payment, echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	switch c.QueryParam("tipo") {
+	case "", "dinamico":
+		payment, _, err = s.pay(&p, paymentRequest{Method: payments.Pix})
+		return
+	case "estatico":
+		return s.staticPix(&p)
+	default:
+		return payment, echo.NewHTTPError(http.StatusBadRequest, errors.New("tipo must be dinamico or estatico"))
+	}
+}
+
+// staticPix records the static Pix code of the accepted offer,
+// identified by the quote ID the payer sends along. Requests made
+// at the same time all answer the code recorded first
+func (s *Server) staticPix(p *models.Profile) (payment models.Payment, err error) {
+	if p.Offer.CurrentStatus() != models.OfferAccepted {
+		return payment, echo.NewHTTPError(http.StatusConflict, errors.New("Only accepted offers can be paid"))
+	}
+	payment, err = s.Payments.Get(p.Offer.ID)
+	if err == nil {
+		return
+	} else if err != models.ErrPaymentNotFound {
+		return payment, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	code, err := s.Pix.Static(p.Offer.TotalValue, p.Offer.ID, "Mudança "+p.Offer.Date)
+	if err == pix.ErrNoKey {
+		return payment, echo.NewHTTPError(http.StatusServiceUnavailable, err)
+	} else if err != nil {
+		return payment, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	now := time.Now()
+	payment = models.Payment{
+		ID:        p.Offer.ID,
+		Profile:   p.ID,
+		QuoteID:   p.Offer.ID,
+		Method:    string(payments.Pix),
+		Amount:    p.Offer.TotalValue,
+		Status:    models.PaymentPending,
+		PixCode:   code,
+		Static:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err = s.Payments.Create(payment)
+	if err == models.ErrPaymentExists {
+		// recorded by a request made at the same time
+		if payment, err = s.Payments.Get(p.Offer.ID); err != nil {
+			return payment, echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+	} else if err != nil {
+		return payment, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return
+}
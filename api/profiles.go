@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/geo"
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// registration is the onboarding payload of a new profile.
+// "modelo" names an inventory template and "inventario" lists
+// the rooms, boxes and items, so the user does not start from
+// scratch. Only one of them may be given. "paradas" are the
+// stops between the current and the new address
+type registration struct {
+	Template       string         `json:"modelo"`
+	CurrentAddress models.Address `json:"endereco_atual"`
+	NewAddress     models.Address `json:"endereco_novo"`
+	Stops          []models.Stop  `json:"paradas"`
+	MovingData     time.Time      `json:"data_mudanca"`
+	MovingTime     time.Time      `json:"horario_mudanca"`
+	Inventory      []models.Room  `json:"inventario"`
+}
+
+// RegisterProfile creates a profile owned by the authenticated
+// phone number and returns it with the number used to reach it
+// Addresses are completed from their CEP and geocoded when
+// they come without coordinates
+// POST /profiles {"endereco_atual": {"numero": 259, "complemento": "apto 101", "cep": "20271-160"},
+// "endereco_novo": {...}, "data_mudanca": "2019-03-01T09:00:00Z", "modelo": "studio"}
+//
+// HTTP responses:
+// 201 created
+// 400 bad request
+// 401 unauthorized
+// 409 conflict, the phone number already has a profile
+// 500 internal server error
+// 502 bad gateway
+func (s *Server) RegisterProfile(c echo.Context) (err error) {
+	var req registration
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	// checked before resolving the addresses, Create checks again
+	if _, err = s.Profiles.FindByPhone(authPhone(c)); err == nil {
+		return echo.NewHTTPError(http.StatusConflict, models.ErrPhoneTaken)
+	} else if err != models.ErrProfileNotFound {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	p := models.Profile{
+		Phone:          authPhone(c),
+		CurrentAddress: req.CurrentAddress,
+		NewAddress:     req.NewAddress,
+		MovingData:     req.MovingData,
+		MovingTime:     req.MovingTime,
+		Offer:          models.Offer{Status: models.OfferDraft},
+	}
+	if err = s.resolveAddress("endereco_atual", &p.CurrentAddress); err != nil {
+		return
+	}
+	if err = s.resolveAddress("endereco_novo", &p.NewAddress); err != nil {
+		return
+	}
+	if err = p.ValidateMove(time.Now()); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	for i := range req.Stops {
+		req.Stops[i].ID = ""
+	}
+	if err = s.setStops(&p, req.Stops); err != nil {
+		return
+	}
+	if req.Template != "" {
+		if len(req.Inventory) > 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.New("Give either modelo or inventario"))
+		}
+		t, errT := s.Templates.Get(req.Template)
+		if errT == models.ErrTemplateNotFound {
+			return echo.NewHTTPError(http.StatusBadRequest, errT)
+		} else if errT != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, errT)
+		}
+		req.Inventory = t.Inventory
+	} else if err = s.prepareInventory(req.Inventory); err != nil {
+		return
+	}
+	if p.Inventory, err = models.NewInventory(req.Inventory); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	err = s.Profiles.Create(&p)
+	if err == models.ErrPhoneTaken || err == models.ErrProfileExists {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusCreated, p)
+}
+
+// resolveAddress completes an address from its CEP and geocodes it
+func (s *Server) resolveAddress(name string, a *models.Address) error {
+	err := s.Addresses.Resolve(a)
+	if err == geo.ErrCEPNotFound || err == geo.ErrAddressNotFound {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("%s: %v", name, err))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, fmt.Errorf("%s: %v", name, err))
+	}
+	return nil
+}
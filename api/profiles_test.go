@@ -0,0 +1,75 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/MudaeH5A/4thinkbe/payments"
+)
+
+// TestMoveFlow books, accepts, pays and cancels a move three
+// days ahead, when the default policy keeps 30% of the price
+func TestMoveFlow(t *testing.T) {
+	s := newTestServer(t)
+	c := login(t, s, "+5521999999999")
+	day := moveDay(3)
+	date := day.Format(models.DateLayout)
+	p := c.register(day)
+	c.must(http.StatusConflict, "POST", "/profiles", map[string]interface{}{}, nil)
+	login(t, s, "+5521988888888").must(http.StatusForbidden, "GET", profilePath(p, ""), nil, nil)
+
+	var quoted models.Offer
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, &quoted)
+	if quoted.CurrentStatus() != models.OfferQuoted || quoted.Date != date || quoted.TotalValue <= 0 {
+		t.Fatalf("quote %s for %s at %v", quoted.CurrentStatus(), quoted.Date, quoted.TotalValue)
+	}
+	c.must(http.StatusConflict, "POST", profilePath(p, "/offer/accept"), nil, nil)
+	c.must(http.StatusCreated, "POST", profilePath(p, "/schedule"), bookingRequest{Date: date, SlotID: "2-manha"}, nil)
+	var offer models.Offer
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/accept"), acceptRequest{QuoteID: quoted.ID}, &offer)
+	if offer.CurrentStatus() != models.OfferAccepted || offer.TotalValue != quoted.TotalValue {
+		t.Fatalf("offer %s at %v", offer.CurrentStatus(), offer.TotalValue)
+	}
+	if booked, _ := s.Bookings.Booked(date); booked["2-manha"] != 1 {
+		t.Fatalf("booked %v", booked)
+	}
+
+	var payment models.Payment
+	c.must(http.StatusCreated, "POST", profilePath(p, "/offer/payment"), paymentRequest{Method: payments.Pix}, &payment)
+	if payment.Amount != offer.TotalValue || payment.PixCode == "" {
+		t.Fatalf("payment %+v", payment)
+	}
+	webhook(t, s, "evt_paid", payments.ChargePaid, payment.ID, payment.Amount, http.StatusOK)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if p.Offer.CurrentStatus() != models.OfferPaid || p.Offer.Payment != payment.ID {
+		t.Fatalf("offer %s paid by %q", p.Offer.CurrentStatus(), p.Offer.Payment)
+	}
+
+	var terms models.Cancellation
+	c.must(http.StatusOK, "GET", profilePath(p, "/offer/cancellation"), nil, &terms)
+	refund := math.Round(offer.TotalValue*(100-30)) / 100
+	if terms.Fee != 30 || terms.Refund != refund {
+		t.Errorf("terms %+v, want a refund of %v", terms, refund)
+	}
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/cancel"), cancelRequest{Reason: "Mudei de data"}, &offer)
+	cancellation := offer.Cancellation
+	if offer.CurrentStatus() != models.OfferCancelled || cancellation == nil {
+		t.Fatalf("offer %s", offer.CurrentStatus())
+	}
+	if cancellation.Reason != "Mudei de data" || cancellation.Paid != payment.Amount || cancellation.Refund != refund ||
+		cancellation.RefundStatus != models.RefundRequested || cancellation.RefundID == "" {
+		t.Errorf("cancellation %+v", cancellation)
+	}
+	if booked, _ := s.Bookings.Booked(date); booked["2-manha"] != 0 {
+		t.Errorf("booked %v after cancelling", booked)
+	}
+	c.must(http.StatusConflict, "POST", profilePath(p, "/offer/cancel"), nil, nil)
+
+	// a new quote can be made afterwards
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, &quoted)
+	if quoted.CurrentStatus() != models.OfferQuoted {
+		t.Errorf("quote %s after cancelling", quoted.CurrentStatus())
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/geo"
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// QuoteHandler prices the move with every vehicle type at once
+// and recommends one based on the size of the inventory.
+// Nothing is stored, the user still picks the vehicle with
+// POST /:userNumber/:vehicle
+// GET /:userNumber/quotes
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 500 internal server error
+func (s *Server) QuoteHandler(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	tariff, err := s.Tariffs.Current(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	distance, err := geo.RouteDistance(s.Distance, p.Route())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	catalog, err := s.catalog()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	move, err := s.move(&p, catalog)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	quote, err := models.NewQuote(tariff, distance, move)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, quote)
+}
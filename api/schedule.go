@@ -0,0 +1,294 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// slotAvailability is a slot with how many vehicles
+// are still free on a date
+type slotAvailability struct {
+	models.Slot
+	Free int `json:"livres"`
+}
+
+// bookingRequest chooses the slot of the move
+type bookingRequest struct {
+	Date   string `json:"data"`
+	SlotID string `json:"horario"`
+}
+
+// ListSlots returns the slots of a date with the number of
+// vehicles still free, optionally of a single vehicle type
+// GET /schedule?data=2019-03-01&veiculo=2
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 500 internal server error
+func (s *Server) ListSlots(c echo.Context) (err error) {
+	date := c.QueryParam("data")
+	if _, err = time.Parse(models.DateLayout, date); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.New("data must be written as 2006-01-02"))
+	}
+	vehicle := 0
+	if v := c.QueryParam("veiculo"); v != "" {
+		if vehicle, err = strconv.Atoi(v); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+	}
+	slots, err := s.Slots.List()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	booked, err := s.Bookings.Booked(date)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	availability := []slotAvailability{}
+	for _, slot := range slots {
+		if vehicle != 0 && slot.VehicleType != vehicle {
+			continue
+		}
+		free := slot.Capacity - booked[slot.ID]
+		if free < 0 {
+			free = 0
+		}
+		availability = append(availability, slotAvailability{slot, free})
+	}
+	return c.JSON(http.StatusOK, availability)
+}
+
+// BookSlot reserves a slot of the offer vehicle type for the
+// move, releasing the slot booked before, if any. The moving
+// date and time become the start of the slot. Accepted and
+// paid offers can move to another slot of the day they
+// were priced for
+// POST /:userNumber/schedule {"data": "2019-03-01", "horario": "2-manha"}
+//
+// HTTP responses:
+// 201 created
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+func (s *Server) BookSlot(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var req bookingRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	switch p.Offer.CurrentStatus() {
+	case models.OfferQuoted:
+	case models.OfferAccepted, models.OfferPaid:
+		if p.Offer.Date != "" && req.Date != p.Offer.Date {
+			return echo.NewHTTPError(http.StatusConflict, models.ErrQuoteOtherDate)
+		}
+	default:
+		return echo.NewHTTPError(http.StatusConflict, errors.New("Choose a vehicle before booking"))
+	}
+	offer := p.Offer
+	previous, err := s.reserve(&p, req)
+	if err != nil {
+		return
+	}
+	if err = s.saveOffer(&p, offer); err != nil {
+		s.releaseReplaced(p.ID, p.Booking, previous)
+		return
+	}
+	s.releaseReplaced(p.ID, previous, p.Booking)
+	return c.JSON(http.StatusCreated, p.Booking)
+}
+
+// ReleaseSlot gives up the slot booked for the move.
+// Accepted offers must be cancelled instead
+// DELETE /:userNumber/schedule
+//
+// HTTP responses:
+// 204 no content
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+func (s *Server) ReleaseSlot(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	if p.Booking == nil {
+		return echo.NewHTTPError(http.StatusNotFound, models.ErrBookingNotFound)
+	}
+	if status := p.Offer.CurrentStatus(); status != models.OfferDraft && status != models.OfferQuoted {
+		return echo.NewHTTPError(http.StatusConflict, errors.New("Cancel the offer to release its slot"))
+	}
+	booking := p.Booking
+	p.Booking = nil
+	if err = s.saveOffer(&p, p.Offer); err != nil {
+		return
+	}
+	s.release(p.ID, booking)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// reserve books the requested slot for the offer vehicle type
+// and sets it in the profile. It returns the booking replaced,
+// to be released once the profile is saved
+func (s *Server) reserve(p *models.Profile, req bookingRequest) (previous *models.Booking, err error) {
+	slot, err := s.Slots.Get(req.SlotID)
+	if err == models.ErrSlotNotFound {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err)
+	} else if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if slot.VehicleType != p.Offer.VehicleType {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors.New("Slot is for another vehicle type"))
+	}
+	booking, err := slot.On(req.Date)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors.New("data must be written as 2006-01-02"))
+	}
+	if !booking.Start.After(time.Now()) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errors.New("Slot has already started"))
+	}
+	if err = s.Bookings.Book(booking.Date, slot, p.ID); err == models.ErrSlotFull {
+		return nil, echo.NewHTTPError(http.StatusConflict, err)
+	} else if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	previous = p.Booking
+	p.Book(booking)
+	return previous, nil
+}
+
+// utilisation returns, for each vehicle type, the share of
+// its slot capacity already booked on the date, leaving
+// out the booking of the user asking
+func (s *Server) utilisation(date string, own *models.Booking) (map[int]float64, error) {
+	slots, err := s.Slots.List()
+	if err != nil {
+		return nil, err
+	}
+	booked, err := s.Bookings.Booked(date)
+	if err != nil {
+		return nil, err
+	}
+	if own != nil && own.Date == date && booked[own.SlotID] > 0 {
+		booked[own.SlotID]--
+	}
+	capacity, used := make(map[int]int), make(map[int]int)
+	for _, slot := range slots {
+		capacity[slot.VehicleType] += slot.Capacity
+		used[slot.VehicleType] += booked[slot.ID]
+	}
+	shares := make(map[int]float64, len(capacity))
+	for vehicle, c := range capacity {
+		if c > 0 {
+			shares[vehicle] = float64(used[vehicle]) / float64(c)
+		}
+	}
+	return shares, nil
+}
+
+// move gathers what prices the move of the profile,
+// with the demand for vehicles on its moving day
+func (s *Server) move(p *models.Profile, catalog models.Catalog) (m models.Move, err error) {
+	m = p.Move(catalog)
+	if !m.Date.IsZero() {
+		m.Utilisation, err = s.utilisation(m.Date.Format(models.DateLayout), p.Booking)
+	}
+	return
+}
+
+// releaseReplaced frees the previous booking unless
+// the same slot was booked again
+func (s *Server) releaseReplaced(profile int, previous, current *models.Booking) {
+	if previous == nil {
+		return
+	}
+	if current != nil && previous.Date == current.Date && previous.SlotID == current.SlotID {
+		return
+	}
+	s.release(profile, previous)
+}
+
+// release frees a booked slot. Failing to do so only
+// leaves a vehicle unused, so it is logged
+func (s *Server) release(profile int, b *models.Booking) {
+	err := s.Bookings.Release(b.Date, b.SlotID, profile)
+	if err != nil && err != models.ErrBookingNotFound {
+		log.Printf("releasing slot %s on %s of profile %d: %v", b.SlotID, b.Date, profile, err)
+	}
+}
+
+// ListSlotDefinitions returns every daily slot
+// GET /admin/slots
+//
+// HTTP responses:
+// 200 ok
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) ListSlotDefinitions(c echo.Context) (err error) {
+	slots, err := s.Slots.List()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, slots)
+}
+
+// SaveSlot creates or replaces a daily slot. Lowering the
+// capacity keeps the bookings already made
+// PUT /admin/slots/:id {"veiculo": 2, "inicio": "08:00", "fim": "12:00", "capacidade": 2}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) SaveSlot(c echo.Context) (err error) {
+	var slot models.Slot
+	if err = c.Bind(&slot); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	slot.ID = c.Param("id")
+	if err = slot.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err = s.Slots.Save(slot); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, slot)
+}
+
+// DeleteSlot removes a daily slot. Moves already
+// booked in it are kept
+// DELETE /admin/slots/:id
+//
+// HTTP responses:
+// 204 no content
+// 401 unauthorized
+// 404 not found
+func (s *Server) DeleteSlot(c echo.Context) (err error) {
+	if err = s.Slots.Delete(c.Param("id")); err == models.ErrSlotNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
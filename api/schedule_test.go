@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+func TestBookAnotherDayThenAccept(t *testing.T) {
+	s := newTestServer(t)
+	c := login(t, s, "+5521999999999")
+	p := c.register(moveDay(10))
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, nil)
+
+	other := moveDay(11).Format(models.DateLayout)
+	var booking models.Booking
+	c.must(http.StatusCreated, "POST", profilePath(p, "/schedule"), bookingRequest{Date: other, SlotID: "2-manha"}, &booking)
+	// the quote was priced for the registered day
+	c.must(http.StatusConflict, "POST", profilePath(p, "/offer/accept"), nil, nil)
+
+	var stored models.Profile
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &stored)
+	if !stored.MovingTime.Equal(booking.Start) || stored.MovingData.In(models.MovingLocation).Format(models.DateLayout) != other {
+		t.Fatalf("moving date %s and time %s after booking %s", stored.MovingData, stored.MovingTime, other)
+	}
+
+	var offer models.Offer
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, &offer)
+	if offer.Date != other {
+		t.Fatalf("quote priced for %s, want %s", offer.Date, other)
+	}
+	c.must(http.StatusOK, "POST", profilePath(p, "/offer/accept"), nil, &offer)
+	if offer.CurrentStatus() != models.OfferAccepted {
+		t.Errorf("offer %s after accepting", offer.CurrentStatus())
+	}
+}
@@ -1,40 +1,275 @@
 package api
 
 import (
-	"encoding/json"
-	"errors"
-	"fmt"
+	"crypto/rand"
 	"html/template"
-	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/MudaeH5A/4thinkbe/auth"
 	"github.com/MudaeH5A/4thinkbe/db"
+	"github.com/MudaeH5A/4thinkbe/geo"
+	"github.com/MudaeH5A/4thinkbe/labels"
 	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/MudaeH5A/4thinkbe/payments"
+	"github.com/MudaeH5A/4thinkbe/pix"
 	"github.com/labstack/echo"
 	qrcode "github.com/skip2/go-qrcode"
-	"gopkg.in/mgo.v2"
 )
 
 type Server struct {
-	Storage *mgo.Database
-	Port    string
+	Profiles  models.ProfileRepository
+	Tariffs   models.TariffRepository
+	Catalog   models.CatalogRepository
+	Templates models.TemplateRepository
+	Slots     models.SlotRepository
+	Bookings  models.BookingRepository
+	Coupons   models.CouponRepository
+	Payments  models.PaymentRepository
+	Distance  geo.DistanceProvider
+	Addresses *geo.Resolver
+	Boxes     *labels.Signer
+	OTPs      models.OTPRepository
+	// OTPKey keys the hashes of the stored OTP codes
+	OTPKey   []byte
+	Sessions *auth.Issuer
+	SMS      auth.SMSSender
+	Gateway  payments.PaymentProvider
+	// Pix is the account receiving static Pix payments
+	Pix       pix.Merchant
+	PublicURL string
+	// QuoteValidity is how long a quoted price can be accepted
+	QuoteValidity time.Duration
+	AdminToken    string
+	Port          string
 }
 
 func New() *Server {
-	return &Server{Port: port(), Storage: db.Connection()}
+	s := &Server{
+		Port:          port(),
+		AdminToken:    os.Getenv("ADMIN_TOKEN"),
+		PublicURL:     publicURL(),
+		Boxes:         labels.NewSigner(secret("QR_SECRET")),
+		Sessions:      auth.NewIssuer(secret("JWT_SECRET"), 30*24*time.Hour),
+		OTPKey:        secret("OTP_SECRET"),
+		QuoteValidity: quoteValidity(),
+		Pix:           pix.MerchantFromEnv(),
+	}
+	s.connect()
+	if err := models.SeedTariffs(s.Tariffs, initialTariffs()); err != nil {
+		log.Fatal(err)
+	}
+	if err := models.SeedCatalog(s.Catalog, initialCatalog()); err != nil {
+		log.Fatal(err)
+	}
+	if err := models.SeedTemplates(s.Templates, s.Catalog, initialTemplates()); err != nil {
+		log.Fatal(err)
+	}
+	if err := models.SeedSlots(s.Slots, initialSlots()); err != nil {
+		log.Fatal(err)
+	}
+	distance, err := geo.DistanceFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.Distance = distance
+	if s.Addresses, err = geo.ResolverFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if s.SMS, err = auth.SMSFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	if s.Gateway, err = payments.FromEnv(secret("PAYMENT_WEBHOOK_SECRET"), s.Pix); err != nil {
+		log.Fatal(err)
+	}
+	return s
+}
+
+// connect uses MongoDB when MONGODB_URI is set
+// and falls back to in-memory storage otherwise
+func (s *Server) connect() {
+	if os.Getenv("MONGODB_URI") == "" {
+		log.Print("MONGODB_URI is not set, data will be kept in memory")
+		s.Profiles = models.NewMemoryRepository()
+		s.Tariffs = models.NewMemoryTariffRepository()
+		s.Catalog = models.NewMemoryCatalogRepository()
+		s.Templates = models.NewMemoryTemplateRepository()
+		s.Slots = models.NewMemorySlotRepository()
+		s.Bookings = models.NewMemoryBookingRepository()
+		s.Coupons = models.NewMemoryCouponRepository()
+		s.Payments = models.NewMemoryPaymentRepository()
+		s.OTPs = models.NewMemoryOTPRepository()
+		return
+	}
+	database := db.Connection()
+	profiles := models.NewMongoRepository(database)
+	migrated, err := profiles.MigrateBoxIDs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if migrated > 0 {
+		log.Printf("assigned box IDs in %d profiles", migrated)
+	}
+	if err = profiles.EnsureIndexes(); err != nil {
+		log.Fatal(err)
+	}
+	s.Profiles = profiles
+	s.Tariffs = models.NewMongoTariffRepository(database)
+	s.Catalog = models.NewMongoCatalogRepository(database)
+	s.Templates = models.NewMongoTemplateRepository(database)
+	s.Slots = models.NewMongoSlotRepository(database)
+	bookings := models.NewMongoBookingRepository(database)
+	if err = bookings.EnsureIndexes(); err != nil {
+		log.Fatal(err)
+	}
+	s.Bookings = bookings
+	s.Coupons = models.NewMongoCouponRepository(database)
+	charges := models.NewMongoPaymentRepository(database)
+	if err = charges.EnsureIndexes(); err != nil {
+		log.Fatal(err)
+	}
+	s.Payments = charges
+	otps := models.NewMongoOTPRepository(database)
+	if err = otps.EnsureIndexes(); err != nil {
+		log.Fatal(err)
+	}
+	s.OTPs = otps
+}
+
+// initialTariffs are stored when there is no tariff yet,
+// read from TARIFFS_FILE if set
+func initialTariffs() []models.Tariff {
+	path := os.Getenv("TARIFFS_FILE")
+	if path == "" {
+		return []models.Tariff{models.DefaultTariff()}
+	}
+	tariffs, err := models.LoadTariffs(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return tariffs
+}
+
+// initialCatalog is stored when the catalog is empty,
+// read from CATALOG_FILE if set
+func initialCatalog() []models.CatalogItem {
+	path := os.Getenv("CATALOG_FILE")
+	if path == "" {
+		return models.DefaultCatalog()
+	}
+	items, err := models.LoadCatalog(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return items
+}
+
+// initialTemplates is stored when there are no inventory
+// templates, read from TEMPLATES_FILE if set
+func initialTemplates() []models.InventoryTemplate {
+	path := os.Getenv("TEMPLATES_FILE")
+	if path == "" {
+		return models.DefaultTemplates()
+	}
+	templates, err := models.LoadTemplates(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return templates
+}
+
+// initialSlots is stored when there are no
+// slots, read from SLOTS_FILE if set
+func initialSlots() []models.Slot {
+	path := os.Getenv("SLOTS_FILE")
+	if path == "" {
+		return models.DefaultSlots()
+	}
+	slots, err := models.LoadSlots(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return slots
 }
 
 func (s *Server) Listen() {
+	e := s.Router()
+	e.Logger.Fatal(e.Start(":" + s.Port))
+}
+
+// Router registers every route of the API
+func (s *Server) Router() *echo.Echo {
 	e := echo.New()
 	e.Static("/static", "assets")
-	e.GET("/:userNumber", s.HomeHandler)
-	e.GET("/:userNumber/:room/:boxNumber/code", s.BoxCoder)
-	e.GET("/:userNumber/:room/:boxNumber", s.BoxContent)
-	e.POST("/:userNumber/:vehicle", s.VehicleHandler)
-	e.Logger.Fatal(e.Start(":" + port()))
+	e.GET("/b/:token", s.BoxContent)
+	e.GET("/catalog", s.ListCatalog)
+	e.GET("/templates", s.ListTemplates)
+	e.GET("/schedule", s.ListSlots)
+	e.POST("/auth/otp", s.RequestOTP)
+	e.POST("/auth/verify", s.VerifyOTP)
+	e.POST("/payments/webhook", s.PaymentWebhook)
+	e.POST("/profiles", s.RegisterProfile, s.authenticated)
+
+	// every route below only serves the owner of the profile
+	owner := s.ownsProfile
+	e.GET("/:userNumber", s.HomeHandler, owner)
+	e.POST("/:userNumber/:vehicle", s.VehicleHandler, owner)
+	e.GET("/:userNumber/quotes", s.QuoteHandler, owner)
+	e.GET("/:userNumber/summary", s.InventorySummary, owner)
+	e.POST("/:userNumber/offer/accept", s.AcceptOffer, owner)
+	e.POST("/:userNumber/offer/cancel", s.CancelOffer, owner)
+	e.GET("/:userNumber/offer/cancellation", s.CancellationTerms, owner)
+	e.POST("/:userNumber/offer/coupon", s.ApplyCoupon, owner)
+	e.DELETE("/:userNumber/offer/coupon", s.RemoveCoupon, owner)
+	e.POST("/:userNumber/offer/payment", s.CreatePayment, owner)
+	e.GET("/:userNumber/offer/payments", s.ListPayments, owner)
+	e.GET("/:userNumber/offer/pix", s.PixCode, owner)
+	e.GET("/:userNumber/offer/pix.png", s.PixQRCode, owner)
+	e.POST("/:userNumber/schedule", s.BookSlot, owner)
+	e.DELETE("/:userNumber/schedule", s.ReleaseSlot, owner)
+	e.GET("/:userNumber/labels", s.LabelSheet, owner)
+	e.GET("/:userNumber/rooms/:room/labels", s.LabelSheet, owner)
+	e.GET("/:userNumber/stops", s.ListStops, owner)
+	e.PUT("/:userNumber/stops", s.SetStops, owner)
+	e.GET("/:userNumber/rooms", s.ListRooms, owner)
+	e.POST("/:userNumber/rooms", s.CreateRoom, owner)
+	e.PUT("/:userNumber/rooms/:room", s.RenameRoom, owner)
+	e.DELETE("/:userNumber/rooms/:room", s.DeleteRoom, owner)
+	e.PUT("/:userNumber/rooms/:room/stop", s.SetRoomStop, owner)
+	e.POST("/:userNumber/rooms/:room/boxes", s.CreateBox, owner)
+	e.DELETE("/:userNumber/rooms/:room/boxes/:boxID", s.DeleteBox, owner)
+	e.GET("/:userNumber/rooms/:room/boxes/:boxID/code", s.BoxCoder, owner)
+	e.PUT("/:userNumber/rooms/:room/boxes/:boxID/stop", s.SetBoxStop, owner)
+	e.POST("/:userNumber/rooms/:room/boxes/:boxID/items", s.CreateItem, owner)
+	e.PUT("/:userNumber/rooms/:room/boxes/:boxID/items/:item", s.UpdateItem, owner)
+	e.DELETE("/:userNumber/rooms/:room/boxes/:boxID/items/:item", s.DeleteItem, owner)
+
+	admin := e.Group("/admin", s.adminOnly)
+	admin.GET("/tariffs", s.ListTariffs)
+	admin.POST("/tariffs", s.CreateTariff)
+	admin.GET("/tariffs/current", s.CurrentTariff)
+	admin.GET("/tariffs/:version", s.GetTariff)
+	admin.DELETE("/tariffs/:version", s.DeleteTariff)
+	admin.PUT("/catalog/:id", s.SaveCatalogItem)
+	admin.DELETE("/catalog/:id", s.DeleteCatalogItem)
+	admin.PUT("/templates/:id", s.SaveTemplate)
+	admin.DELETE("/templates/:id", s.DeleteTemplate)
+	admin.GET("/slots", s.ListSlotDefinitions)
+	admin.PUT("/slots/:id", s.SaveSlot)
+	admin.DELETE("/slots/:id", s.DeleteSlot)
+	admin.GET("/coupons", s.ListCoupons)
+	admin.PUT("/coupons/:code", s.SaveCoupon)
+	admin.DELETE("/coupons/:code", s.DeleteCoupon)
+	admin.PUT("/profiles/:userNumber/owner", s.SetProfileOwner)
+	admin.PUT("/profiles/:userNumber/offer/status", s.SetOfferStatus)
+	admin.POST("/profiles/:userNumber/offer/refund", s.RefundOffer)
+	admin.GET("/payments/refunds", s.ListRefundsDue)
+	admin.POST("/payments/:payment/refund", s.RefundPayment)
+	return e
 }
 
 func port() (p string) {
@@ -44,72 +279,78 @@ func port() (p string) {
 	return "8080"
 }
 
-// HomeHandler populates a new user if it does not exists
-// or just returns it if It is existant in the DB
+// quoteValidity reads QUOTE_VALIDITY as a duration, 48h by default
+func quoteValidity() time.Duration {
+	v := os.Getenv("QUOTE_VALIDITY")
+	if v == "" {
+		return 48 * time.Hour
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("QUOTE_VALIDITY must be a positive duration such as 48h: %q", v)
+	}
+	return d
+}
+
+// publicURL is where users reach the API, used in QR codes
+func publicURL() (u string) {
+	if u = os.Getenv("PUBLIC_URL"); u != "" {
+		return strings.TrimRight(u, "/")
+	}
+	return "https://mudae.herokuapp.com"
+}
+
+// secret reads a signing key from the environment. Without it a
+// random key is used, so whatever was signed stops being valid
+// when the server restarts
+func secret(name string) []byte {
+	if key := os.Getenv(name); key != "" {
+		return []byte(key)
+	}
+	log.Printf("%s is not set, using a random key", name)
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatal(err)
+	}
+	return key
+}
+
+// HomeHandler returns the profile of the user
 // GET /:userNumber
 // HTTP responses:
 // 200 ok
 // 400 bad request
+// 404 not found
 // 500 internal server error
 func (s *Server) HomeHandler(c echo.Context) (err error) {
-	userNumber := c.Param("userNumber")
-	number, err := strconv.Atoi(userNumber)
+	number, err := userNumber(c)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
-	p, err := models.GetByID(s.Storage, number)
-	if (err != nil) && (err != mgo.ErrNotFound) {
-		return echo.NewHTTPError(500, err)
-	}
-	if err == mgo.ErrNotFound {
-		r := models.Room{
-			Name: "sala",
-			Boxes: []models.Box{
-				models.Box{
-					Items: []models.Item{
-						models.Item{
-							Quantity: 2,
-							Type:     "moveis",
-						},
-					},
-				},
-				models.Box{
-					Items: []models.Item{
-						models.Item{
-							Quantity: 1,
-							Type:     "tv",
-						},
-					},
-				},
-			},
-		}
-		ca := models.Address{Street: "Santa Luiza", Number: 259, Latitude: -22.9163398, Longitude: -43.2341546}
-		na := models.Address{Street: "Av Paulista", Number: 2537, Latitude: -23.5604276, Longitude: -46.6579269}
-		offer := models.Offer{}
-		p = models.Profile{
-			ID:             number,
-			Inventory:      []models.Room{r},
-			NewAddress:     na,
-			CurrentAddress: ca,
-			MovingData:     time.Now(),
-			MovingTime:     time.Now(),
-			Offer:          offer,
-		}
-		errC := models.Create(s.Storage, p)
-		if errC != nil {
-			return echo.NewHTTPError(500, errC)
-		}
+	p, err := s.Profiles.GetByID(number)
+	if err == models.ErrProfileNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
-	return c.JSON(http.StatusFound, p)
+	return c.JSON(http.StatusOK, p)
 }
 
 // VehicleHandler handles the user offer infos
-// it helps adding data to the user
-// POST /:userNumber/:vehicleNumber [1-3]
+// it helps adding data to the user, pricing it
+// with the tariff currently in effect. A vehicle too
+// small for the inventory is priced with the trips it
+// needs and the offer carries a warning and a suggestion.
+// The new quote replaces the offer, which goes to the
+// history, unless it was already accepted. Its price is
+// locked until the quote expires
+// POST /:userNumber/:vehicleNumber (a vehicle type of the tariff)
 //
 // HTTP responses:
 // 201 created
 // 400 bad request
+// 404 not found
+// 409 conflict
 // 500 internal server error
 func (s *Server) VehicleHandler(c echo.Context) (err error) {
 	userNumber := c.Param("userNumber")
@@ -122,38 +363,97 @@ func (s *Server) VehicleHandler(c echo.Context) (err error) {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
-	if (vehicle > 3) || (vehicle < 1) {
-		return echo.NewHTTPError(http.StatusBadRequest, errors.New("Vehicle type can only be between [1-3]"))
+	tariff, err := s.Tariffs.Current(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if _, err = tariff.Rate(vehicle); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
-	p, err := models.GetByID(s.Storage, number)
+	p, err := s.Profiles.GetByID(number)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, err)
 	}
-	p.Offer.VehicleType = vehicle
-	distance, err := calculateTotalDistance(p.CurrentAddress, p.NewAddress)
+	if !p.Offer.CanGo(models.OfferQuoted) {
+		return echo.NewHTTPError(http.StatusConflict, models.ErrInvalidTransition)
+	}
+	previous := p.Offer
+	offer := models.Offer{VehicleType: vehicle}
+	offer.Distance, err = geo.RouteDistance(s.Distance, p.Route())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	catalog, err := s.catalog()
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
-	p.Offer.Distance = distance
-	p.Offer.CalculateTotalValue()
-	err = p.CreateOrUpdate(s.Storage)
+	move, err := s.move(&p, catalog)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	if err = offer.CheckCapacity(tariff, move.Load); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if err = offer.CalculateTotalValue(tariff, move); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	// the coupon of the replaced quote is given back
+	coupon := p.Offer.Coupon
+	if p.Offer.CurrentStatus() == models.OfferCancelled {
+		coupon = ""
+	}
+	if err = p.Quote(offer, time.Now(), s.QuoteValidity); err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	}
+	// a slot booked for another vehicle type cannot be kept
+	booking := p.Booking
+	if booking != nil {
+		slot, errS := s.Slots.Get(booking.SlotID)
+		if errS != nil && errS != models.ErrSlotNotFound {
+			return echo.NewHTTPError(http.StatusInternalServerError, errS)
+		}
+		if errS == models.ErrSlotNotFound || slot.VehicleType != vehicle {
+			p.Booking = nil
+		}
+	}
+	if err = s.saveOffer(&p, previous); err != nil {
+		return
+	}
+	if booking != nil && p.Booking == nil {
+		s.release(p.ID, booking)
+	}
+	s.releaseCoupon(&p, coupon)
 	return c.JSON(http.StatusCreated, p.Offer)
 }
 
 // BoxCoder generates QR Codes for a specific box
-// GET /:userNumber/:room/:box/code
+// GET /:userNumber/rooms/:room/boxes/:boxID/code
 //
 // HTTP responses:
 // 200 OK
+// 400 bad request
+// 404 not found
 // 500 internal server error
 func (s *Server) BoxCoder(c echo.Context) (err error) {
 	userNumber := c.Param("userNumber")
 	room := c.Param("room")
-	boxNumber := c.Param("boxNumber")
-	appURL := fmt.Sprintf("https://mudae.herokuapp.com/%s/%s/%s", userNumber, room, boxNumber)
+	number, err := strconv.Atoi(userNumber)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	index, err := p.RoomIndex(room)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	boxIndex, err := p.Inventory[index].BoxIndex(c.Param("boxID"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	}
+	appURL := s.boxURL(p.Inventory[index].Boxes[boxIndex])
 	png, err := qrcode.Encode(appURL, qrcode.Medium, 256)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
@@ -169,34 +469,27 @@ func (s *Server) BoxCoder(c echo.Context) (err error) {
 }
 
 // BoxContent lists the contents of a specific
-// box after the QR code call to the API
-// GET /:userNumber/:roomName/:boxNumber
+// box after the QR code call to the API. The token
+// is signed, so boxes cannot be enumerated
+// GET /b/:token
 //
 // HTTP responses:
 // 302 OK
-// 400 bad request
 // 404 not found
 func (s *Server) BoxContent(c echo.Context) (err error) {
-	userNumber := c.Param("userNumber")
-	room := c.Param("room")
-	boxNumber := c.Param("boxNumber")
-	number, err := strconv.Atoi(userNumber)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err)
-	}
-	p, err := models.GetByID(s.Storage, number)
+	boxID, err := s.Boxes.Verify(c.Param("token"))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, err)
 	}
-	index, err := findRoomAndIndex(room, p.Inventory)
+	p, err := s.Profiles.FindBox(boxID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err)
+		return echo.NewHTTPError(http.StatusNotFound, err)
 	}
-	boxInt, err := strconv.Atoi(boxNumber)
+	index, boxIndex, err := p.BoxByID(boxID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err)
+		return echo.NewHTTPError(http.StatusNotFound, err)
 	}
-	data := p.Inventory[index].Boxes[boxInt-1]
+	data := p.Inventory[index].Boxes[boxIndex]
 	tmp := `<style>h1,h3 {
 	color: #37474f;
 	text-shadow: rgba(0, 0, 0, .12) 0 0 1px;
@@ -229,7 +522,7 @@ body {
 <div id="toolbar">
 	<h1>Mudae</h1>
 </div>
-	<h3> Itens da caixa </h3>
+	<h3> Itens da caixa {{.Label}} </h3>
 <ul>
 {{range .Items}}
 	<li>{{.Type}} {{.Quantity}}x</li>
@@ -244,36 +537,7 @@ body {
 	return newTemplate.Execute(resp.Writer, data)
 }
 
-func findRoomAndIndex(name string, rooms []models.Room) (i int, err error) {
-	for i, room := range rooms {
-		if room.Name == name {
-			return i, nil
-		}
-	}
-	return i, errors.New("Room not found")
-}
-
-func calculateTotalDistance(oldAddress, newAddress models.Address) (dist float64, err error) {
-	origins := fmt.Sprintf("origins=%v,%v", oldAddress.Latitude, oldAddress.Longitude)
-	destinations := fmt.Sprintf("destinations=%v,%v", newAddress.Latitude, newAddress.Longitude)
-	apiKey := fmt.Sprintf("key=%s", os.Getenv("MAPS_KEY"))
-	googleMapsEndpoint := fmt.Sprintf("https://maps.googleapis.com/maps/api/distancematrix/json?%s&%s&%s", origins, destinations, apiKey)
-	resp, err := http.Get(googleMapsEndpoint)
-	if err != nil {
-		return
-	}
-	if resp.StatusCode != http.StatusOK {
-		return dist, errors.New(fmt.Sprintf("Google responded with wrong status code: %v", resp.StatusCode))
-	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
-	var mapsResp models.MapsResponse
-	if err = json.Unmarshal(body, &mapsResp); err != nil {
-		return
-	}
-	dist = float64(mapsResp.Rows[0].Elements[0].Distance.Value / 1000)
-	return
+// boxURL is the address a box QR code points to
+func (s *Server) boxURL(box models.Box) string {
+	return s.PublicURL + "/b/" + s.Boxes.Sign(box.ID)
 }
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/auth"
+	"github.com/MudaeH5A/4thinkbe/geo"
+	"github.com/MudaeH5A/4thinkbe/labels"
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/MudaeH5A/4thinkbe/payments"
+	"github.com/MudaeH5A/4thinkbe/pix"
+	"github.com/labstack/echo"
+)
+
+const (
+	testAdminToken    = "admin"
+	testWebhookSecret = "whsec"
+)
+
+// newTestServer runs the API on the in-memory repositories with
+// the default tariff, catalog, templates and slots, the built-in
+// geo fixtures and the fake payment provider, so nothing leaves
+// the process
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	merchant := pix.Merchant{Key: "123e4567-e12b-12d1-a456-426655440000", Name: "Mudae", City: "Rio de Janeiro"}
+	fixture := geo.DefaultFixture()
+	s := &Server{
+		Profiles:      models.NewMemoryRepository(),
+		Tariffs:       models.NewMemoryTariffRepository(),
+		Catalog:       models.NewMemoryCatalogRepository(),
+		Templates:     models.NewMemoryTemplateRepository(),
+		Slots:         models.NewMemorySlotRepository(),
+		Bookings:      models.NewMemoryBookingRepository(),
+		Coupons:       models.NewMemoryCouponRepository(),
+		Payments:      models.NewMemoryPaymentRepository(),
+		OTPs:          models.NewMemoryOTPRepository(),
+		Distance:      geo.Fallback{geo.NewHaversine()},
+		Addresses:     &geo.Resolver{CEP: fixture, Geocoder: fixture},
+		Boxes:         labels.NewSigner([]byte("qr")),
+		OTPKey:        []byte("otp"),
+		Sessions:      auth.NewIssuer([]byte("jwt"), time.Hour),
+		SMS:           auth.LogSender{},
+		Gateway:       payments.NewFake([]byte(testWebhookSecret), merchant),
+		Pix:           merchant,
+		PublicURL:     "https://mudae.test",
+		QuoteValidity: time.Hour,
+		AdminToken:    testAdminToken,
+	}
+	if err := models.SeedTariffs(s.Tariffs, []models.Tariff{models.DefaultTariff()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.SeedCatalog(s.Catalog, models.DefaultCatalog()); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.SeedTemplates(s.Templates, s.Catalog, models.DefaultTemplates()); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.SeedSlots(s.Slots, models.DefaultSlots()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// client calls the router of a test server with a fixed
+// Authorization header
+type client struct {
+	t             *testing.T
+	router        *echo.Echo
+	authorization string
+}
+
+// login returns a client with a session of the phone number
+func login(t *testing.T, s *Server, phone string) *client {
+	t.Helper()
+	token, _, err := s.Sessions.Issue(phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &client{t: t, router: s.Router(), authorization: "Bearer " + token}
+}
+
+// admin returns a client carrying the admin token
+func admin(t *testing.T, s *Server) *client {
+	return &client{t: t, router: s.Router(), authorization: "Bearer " + testAdminToken}
+}
+
+// do sends body as JSON, decodes the answer into out when
+// given and returns the status code
+func (c *client) do(method, path string, body, out interface{}) int {
+	c.t.Helper()
+	rec := c.send(method, path, body, nil)
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			c.t.Fatalf("%s %s: %v in %s", method, path, err, rec.Body)
+		}
+	}
+	return rec.Code
+}
+
+// must is do failing the test unless the answer has the status
+func (c *client) must(status int, method, path string, body, out interface{}) {
+	c.t.Helper()
+	if got := c.do(method, path, body, out); got != status {
+		c.t.Fatalf("%s %s = %d, want %d", method, path, got, status)
+	}
+}
+
+func (c *client) send(method, path string, body interface{}, header http.Header) *httptest.ResponseRecorder {
+	c.t.Helper()
+	var data []byte
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		data = b
+	default:
+		var err error
+		if data, err = json.Marshal(b); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	if body != nil {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	if c.authorization != "" {
+		req.Header.Set(echo.HeaderAuthorization, c.authorization)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	c.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// moveDay is a day ahead of today in the moving time zone
+func moveDay(days int) time.Time {
+	y, m, d := time.Now().In(models.MovingLocation).Date()
+	return time.Date(y, m, d+days, 0, 0, 0, 0, models.MovingLocation)
+}
+
+// register creates the profile of the client moving on the day,
+// between two addresses of the geo fixtures, with a box of books
+func (c *client) register(day time.Time) models.Profile {
+	c.t.Helper()
+	var p models.Profile
+	c.must(http.StatusCreated, "POST", "/profiles", map[string]interface{}{
+		"endereco_atual": map[string]interface{}{"numero": 259, "cep": "20271-160"},
+		"endereco_novo":  map[string]interface{}{"numero": 1000, "cep": "22021-001"},
+		"data_mudanca":   day,
+		"inventario": []map[string]interface{}{{
+			"nome":   "sala",
+			"caixas": []map[string]interface{}{{"etiqueta": "livros", "items": []map[string]interface{}{{"catalogo": "caixa", "quantidade": 10}}}},
+		}},
+	}, &p)
+	return p
+}
+
+// profilePath is a route of the profile
+func profilePath(p models.Profile, format string, args ...interface{}) string {
+	return fmt.Sprintf("/%d", p.ID) + fmt.Sprintf(format, args...)
+}
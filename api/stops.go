@@ -0,0 +1,203 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// stopRequest assigns a room or a box to a stop
+type stopRequest struct {
+	StopID string `json:"parada"`
+}
+
+// ListStops returns the stops of the move in route order
+// GET /:userNumber/stops
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+func (s *Server) ListStops(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return inventoryError(err)
+	}
+	if p.Stops == nil {
+		p.Stops = []models.Stop{}
+	}
+	return c.JSON(http.StatusOK, p.Stops)
+}
+
+// SetStops replaces the stops the move goes by between the
+// current and the new address, in route order. Stops sent with
+// their "id" are kept, the others are created. Addresses are
+// completed like the ones of the profile, unless a kept stop is
+// still at the same place. A quote priced for
+// other stops expires and the move must be priced again. Once
+// the offer is accepted its stops can only change by cancelling it
+// PUT /:userNumber/stops [{"nome": "Depósito", "tipo": "coleta", "endereco": {"numero": 100, "cep": "01310-100"}}]
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 404 not found
+// 409 conflict
+// 500 internal server error
+// 502 bad gateway
+func (s *Server) SetStops(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var stops []models.Stop
+	if err = c.Bind(&stops); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	p, err := s.Profiles.GetByID(number)
+	if err != nil {
+		return inventoryError(err)
+	}
+	if err = stopsLocked(&p); err != nil {
+		return
+	}
+	previous := p.Offer
+	before := p.Stops
+	if err = s.setStops(&p, stops); err != nil {
+		return
+	}
+	if stopsChanged(before, p.Stops) {
+		p.Offer.Expire(time.Now())
+	}
+	if err = s.saveOffer(&p, previous); err != nil {
+		return
+	}
+	return c.JSON(http.StatusOK, p.Stops)
+}
+
+// stopsLocked refuses to change the stops of an accepted offer,
+// whose price was locked for the route it was accepted with
+func stopsLocked(p *models.Profile) error {
+	if p.Offer.Locked() {
+		return echo.NewHTTPError(http.StatusConflict, errors.New("Cancel the offer to change its stops"))
+	}
+	return nil
+}
+
+// stopsChanged tells whether the route went by other stops or
+// addresses, the names of the stops not being priced
+func stopsChanged(before, after []models.Stop) bool {
+	if len(before) != len(after) {
+		return true
+	}
+	for i := range before {
+		if before[i].ID != after[i].ID || before[i].Address != after[i].Address {
+			return true
+		}
+	}
+	return false
+}
+
+// setStops completes the stop addresses and sets them on the profile.
+// Kept stops whose address still points to the same place take the
+// stored one, so they are not looked up again
+func (s *Server) setStops(p *models.Profile, stops []models.Stop) error {
+	for i := range stops {
+		if index, err := p.StopIndex(stops[i].ID); err == nil && samePlace(stops[i].Address, p.Stops[index].Address) {
+			a := p.Stops[index].Address
+			a.Floor, a.Elevator, a.ParkingDistance = stops[i].Address.Floor, stops[i].Address.Elevator, stops[i].Address.ParkingDistance
+			stops[i].Address = a
+			continue
+		}
+		if err := s.resolveAddress(fmt.Sprintf("parada %d", i+1), &stops[i].Address); err != nil {
+			return err
+		}
+	}
+	err := p.SetStops(stops)
+	if err == models.ErrStopNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err == models.ErrStopInUse {
+		return echo.NewHTTPError(http.StatusConflict, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+// samePlace tells whether an address sent again matches the
+// stored one it was completed into. Fields left empty are the
+// ones the CEP lookup and the geocoder fill in
+func samePlace(sent, stored models.Address) bool {
+	sent.Normalize()
+	same := func(a, b string) bool { return a == "" || a == b }
+	return sent.CEP == stored.CEP && sent.Number == stored.Number && sent.Complement == stored.Complement &&
+		same(sent.Street, stored.Street) && same(sent.Neighbourhood, stored.Neighbourhood) &&
+		same(sent.City, stored.City) && same(sent.State, stored.State) &&
+		(sent.Latitude == 0 && sent.Longitude == 0 || sent.Latitude == stored.Latitude && sent.Longitude == stored.Longitude)
+}
+
+// SetRoomStop picks up or drops off a room at a stop.
+// An empty "parada" takes it back to the current and new address.
+// Like the stops, it expires the current quote and cannot
+// change once the offer is accepted
+// PUT /:userNumber/rooms/:room/stop {"parada": "5c1d..."}
+//
+// HTTP responses:
+// 204 no content
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) SetRoomStop(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var req stopRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	err = s.editInventory(number, func() error {
+		return s.Profiles.SetRoomStop(number, c.Param("room"), req.StopID)
+	})
+	if err != nil {
+		return
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SetBoxStop picks up or drops off a box at a stop other than
+// the one of its room. An empty "parada" follows the room again.
+// Like the room, it expires the current quote and cannot change
+// once the offer is accepted
+// PUT /:userNumber/rooms/:room/boxes/:boxID/stop {"parada": "5c1d..."}
+//
+// HTTP responses:
+// 204 no content
+// 400 bad request
+// 404 not found
+// 409 conflict
+func (s *Server) SetBoxStop(c echo.Context) (err error) {
+	number, err := userNumber(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	var req stopRequest
+	if err = c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	err = s.editInventory(number, func() error {
+		return s.Profiles.SetBoxStop(number, c.Param("room"), c.Param("boxID"), req.StopID)
+	})
+	if err != nil {
+		return
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/geo"
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+func TestStops(t *testing.T) {
+	s := newTestServer(t)
+	c := login(t, s, "+5521999999999")
+	p := c.register(moveDay(10))
+	var direct models.Offer
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, &direct)
+
+	var stops []models.Stop
+	c.must(http.StatusOK, "PUT", profilePath(p, "/stops"), []models.Stop{
+		{Name: "Depósito", Kind: models.StopPickup, Address: models.Address{Number: 100, CEP: "01310-100"}},
+	}, &stops)
+	if len(stops) != 1 || stops[0].ID == "" || stops[0].Address.City != "São Paulo" {
+		t.Fatalf("stops %+v", stops)
+	}
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if !p.Offer.Expired(time.Now()) {
+		t.Errorf("quote valid until %v after changing the stops", p.Offer.ValidUntil)
+	}
+	var withStop models.Offer
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, &withStop)
+	if withStop.TotalValue <= direct.TotalValue {
+		t.Errorf("price with a stop %v, without %v", withStop.TotalValue, direct.TotalValue)
+	}
+
+	// assigning a room expires the quote and keeps the stop
+	c.must(http.StatusNoContent, "PUT", profilePath(p, "/rooms/sala/stop"), stopRequest{StopID: stops[0].ID}, nil)
+	c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+	if !p.Offer.Expired(time.Now()) {
+		t.Errorf("quote valid until %v after assigning a room", p.Offer.ValidUntil)
+	}
+	c.must(http.StatusNotFound, "PUT", profilePath(p, "/rooms/sala/stop"), stopRequest{StopID: "other"}, nil)
+	c.must(http.StatusConflict, "PUT", profilePath(p, "/stops"), []models.Stop{}, nil)
+
+	c.must(http.StatusNoContent, "PUT", profilePath(p, "/rooms/sala/stop"), stopRequest{}, nil)
+	c.must(http.StatusOK, "PUT", profilePath(p, "/stops"), []models.Stop{}, &stops)
+	if len(stops) != 0 {
+		t.Errorf("stops %+v", stops)
+	}
+}
+
+func TestStopsLockedOnceAccepted(t *testing.T) {
+	s := newTestServer(t)
+	c, p := accepted(t, s, "+5521999999999")
+	box := p.Inventory[0].Boxes[0]
+
+	c.must(http.StatusConflict, "PUT", profilePath(p, "/stops"), []models.Stop{
+		{Name: "Depósito", Kind: models.StopPickup, Address: models.Address{Number: 100, CEP: "01310-100"}},
+	}, nil)
+	c.must(http.StatusConflict, "PUT", profilePath(p, "/rooms/sala/stop"), stopRequest{}, nil)
+	c.must(http.StatusConflict, "PUT", profilePath(p, "/rooms/sala/boxes/%s/stop", box.ID), stopRequest{}, nil)
+}
+
+// countingCEP counts the CEP lookups of a fixture
+type countingCEP struct {
+	geo.Fixture
+	lookups int
+}
+
+func (f *countingCEP) LookupCEP(cep string) (models.Address, error) {
+	f.lookups++
+	return f.Fixture.LookupCEP(cep)
+}
+
+func TestSetStopsKeepsUnchangedStops(t *testing.T) {
+	s := newTestServer(t)
+	cep := &countingCEP{Fixture: geo.DefaultFixture()}
+	s.Addresses.CEP = cep
+	c := login(t, s, "+5521999999999")
+	p := c.register(moveDay(10))
+	quoted := func() bool {
+		t.Helper()
+		c.must(http.StatusOK, "GET", profilePath(p, ""), nil, &p)
+		return !p.Offer.Expired(time.Now())
+	}
+
+	// no stops, sent as an empty list
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, nil)
+	c.must(http.StatusOK, "PUT", profilePath(p, "/stops"), []models.Stop{}, nil)
+	if !quoted() {
+		t.Error("quote expired by setting no stops again")
+	}
+
+	var stops []models.Stop
+	c.must(http.StatusOK, "PUT", profilePath(p, "/stops"), []models.Stop{
+		{Name: "Depósito", Kind: models.StopPickup, Address: models.Address{Number: 100, CEP: "01310-100"}},
+	}, &stops)
+	c.must(http.StatusCreated, "POST", profilePath(p, "/2"), nil, nil)
+
+	// the same stop, sent back as stored or as it was typed and renamed
+	lookups := cep.lookups
+	c.must(http.StatusOK, "PUT", profilePath(p, "/stops"), stops, nil)
+	renamed := models.Stop{ID: stops[0].ID, Name: "Galpão", Kind: models.StopPickup, Address: models.Address{Number: 100, CEP: "01310100"}}
+	c.must(http.StatusOK, "PUT", profilePath(p, "/stops"), []models.Stop{renamed}, &stops)
+	if cep.lookups != lookups {
+		t.Errorf("%d lookups for unchanged stops", cep.lookups-lookups)
+	}
+	if !quoted() {
+		t.Error("quote expired by unchanged stops")
+	}
+	if stops[0].Name != "Galpão" || stops[0].Address.City != "São Paulo" {
+		t.Errorf("stops %+v", stops)
+	}
+
+	// the floor changes the price but needs no lookup
+	renamed.Address.Floor = 3
+	c.must(http.StatusOK, "PUT", profilePath(p, "/stops"), []models.Stop{renamed}, &stops)
+	if cep.lookups != lookups || stops[0].Address.Floor != 3 || stops[0].Address.Latitude == 0 {
+		t.Errorf("%d lookups, stops %+v", cep.lookups-lookups, stops)
+	}
+	if quoted() {
+		t.Error("quote kept after changing the floor of a stop")
+	}
+
+	// another place is looked up
+	renamed.Address = models.Address{Number: 259, CEP: "20271-160"}
+	c.must(http.StatusOK, "PUT", profilePath(p, "/stops"), []models.Stop{renamed}, &stops)
+	if cep.lookups != lookups+1 || stops[0].Address.City != "Rio de Janeiro" {
+		t.Errorf("%d lookups, stops %+v", cep.lookups-lookups, stops)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// ListTariffs returns every tariff version
+// GET /admin/tariffs
+//
+// HTTP responses:
+// 200 ok
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) ListTariffs(c echo.Context) (err error) {
+	tariffs, err := s.Tariffs.List()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, tariffs)
+}
+
+// CurrentTariff returns the tariff used to price offers right now
+// GET /admin/tariffs/current
+//
+// HTTP responses:
+// 200 ok
+// 401 unauthorized
+// 404 not found
+func (s *Server) CurrentTariff(c echo.Context) (err error) {
+	t, err := s.Tariffs.Current(time.Now())
+	if err != nil {
+		return tariffError(err)
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+// GetTariff returns a tariff version
+// GET /admin/tariffs/:version
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 404 not found
+func (s *Server) GetTariff(c echo.Context) (err error) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	t, err := s.Tariffs.Get(version)
+	if err != nil {
+		return tariffError(err)
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+// CreateTariff stores a new tariff version. Tariffs are never
+// changed, a price change is a new version with its own
+// effective date (now when "vigencia" is omitted). The date
+// cannot be in the past, which would change the tariff that
+// was in effect when past offers were priced
+// POST /admin/tariffs {"vigencia": "2019-01-01T00:00:00Z", "veiculos": [{"veiculo": 1, "mao_de_obra": 250, "valor_por_km": 2}]}
+//
+// HTTP responses:
+// 201 created
+// 400 bad request
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) CreateTariff(c echo.Context) (err error) {
+	var t models.Tariff
+	if err = c.Bind(&t); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err = t.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if now := time.Now(); t.EffectiveAt.IsZero() {
+		t.EffectiveAt = now
+	} else if t.EffectiveAt.Before(now) {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.New("Tariff cannot take effect in the past"))
+	}
+	t, err = s.Tariffs.Create(t)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusCreated, t)
+}
+
+// DeleteTariff removes a tariff that is not in effect yet
+// DELETE /admin/tariffs/:version
+//
+// HTTP responses:
+// 204 no content
+// 400 bad request
+// 401 unauthorized
+// 404 not found
+// 409 conflict
+func (s *Server) DeleteTariff(c echo.Context) (err error) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err = s.Tariffs.Delete(version); err != nil {
+		return tariffError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func tariffError(err error) error {
+	switch err {
+	case models.ErrTariffNotFound:
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	case models.ErrTariffInUse:
+		return echo.NewHTTPError(http.StatusConflict, err)
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err)
+}
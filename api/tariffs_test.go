@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+func TestCreateTariff(t *testing.T) {
+	s := newTestServer(t)
+	c := admin(t, s)
+	tariff := models.DefaultTariff()
+
+	tariff.EffectiveAt = time.Now().Add(-time.Hour)
+	c.must(http.StatusBadRequest, "POST", "/admin/tariffs", tariff, nil)
+
+	tariff.EffectiveAt = time.Now().Add(time.Hour)
+	var created models.Tariff
+	c.must(http.StatusCreated, "POST", "/admin/tariffs", tariff, &created)
+	if created.Version != 2 {
+		t.Errorf("created version %d, want 2", created.Version)
+	}
+	var current models.Tariff
+	c.must(http.StatusOK, "GET", "/admin/tariffs/current", nil, &current)
+	if current.Version != 1 {
+		t.Errorf("current version %d before the new one is in effect", current.Version)
+	}
+	c.must(http.StatusConflict, "DELETE", "/admin/tariffs/1", nil, nil)
+	c.must(http.StatusNoContent, "DELETE", "/admin/tariffs/2", nil, nil)
+	c.must(http.StatusNotFound, "DELETE", "/admin/tariffs/2", nil, nil)
+}
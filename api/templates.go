@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+	"github.com/labstack/echo"
+)
+
+// ListTemplates returns the inventory templates a new
+// profile can start from
+// GET /templates
+//
+// HTTP responses:
+// 200 ok
+// 500 internal server error
+func (s *Server) ListTemplates(c echo.Context) (err error) {
+	templates, err := s.Templates.List()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, templates)
+}
+
+// SaveTemplate creates or replaces an inventory template.
+// Its items must reference the catalog
+// PUT /admin/templates/:id {"nome": "Studio", "inventario": [{"nome": "sala", "caixas": [{"etiqueta": "tv", "items": [{"catalogo": "tv", "quantidade": 1}]}]}]}
+//
+// HTTP responses:
+// 200 ok
+// 400 bad request
+// 401 unauthorized
+// 500 internal server error
+func (s *Server) SaveTemplate(c echo.Context) (err error) {
+	var t models.InventoryTemplate
+	if err = c.Bind(&t); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	t.ID = c.Param("id")
+	if err = s.prepareInventory(t.Inventory); err != nil {
+		return
+	}
+	catalog, err := s.catalog()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if err = t.Validate(catalog); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err = s.Templates.Save(t); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, t)
+}
+
+// DeleteTemplate removes an inventory template. Profiles
+// started from it keep their inventory
+// DELETE /admin/templates/:id
+//
+// HTTP responses:
+// 204 no content
+// 401 unauthorized
+// 404 not found
+func (s *Server) DeleteTemplate(c echo.Context) (err error) {
+	if err = s.Templates.Delete(c.Param("id")); err == models.ErrTemplateNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// prepareInventory checks the catalog references of
+// every item in the rooms
+func (s *Server) prepareInventory(rooms []models.Room) error {
+	for _, room := range rooms {
+		for _, box := range room.Boxes {
+			for i := range box.Items {
+				if err := s.prepareItem(&box.Items[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+func TestRegisterFromTemplate(t *testing.T) {
+	s := newTestServer(t)
+	c := login(t, s, "+5521999999999")
+	template, err := s.Templates.Get("studio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p models.Profile
+	c.must(http.StatusCreated, "POST", "/profiles", map[string]interface{}{
+		"endereco_atual": map[string]interface{}{"numero": 259, "cep": "20271-160"},
+		"endereco_novo":  map[string]interface{}{"numero": 1000, "cep": "22021-001"},
+		"data_mudanca":   moveDay(10),
+		"modelo":         "studio",
+	}, &p)
+	if len(p.Inventory) != len(template.Inventory) {
+		t.Fatalf("inventory %+v", p.Inventory)
+	}
+	ids := make(map[string]bool)
+	for i, room := range p.Inventory {
+		if len(room.Boxes) != len(template.Inventory[i].Boxes) {
+			t.Fatalf("room %s has %d boxes", room.Name, len(room.Boxes))
+		}
+		for j, box := range room.Boxes {
+			if box.ID == "" || ids[box.ID] || box.Number != j+1 {
+				t.Errorf("box %d of %s has id %q and number %d", j+1, room.Name, box.ID, box.Number)
+			}
+			ids[box.ID] = true
+		}
+	}
+
+	// another profile from the template gets boxes of its own
+	other := login(t, s, "+5521988888888")
+	var q models.Profile
+	other.must(http.StatusCreated, "POST", "/profiles", map[string]interface{}{
+		"endereco_atual": map[string]interface{}{"numero": 259, "cep": "20271-160"},
+		"endereco_novo":  map[string]interface{}{"numero": 1000, "cep": "22021-001"},
+		"data_mudanca":   moveDay(10),
+		"modelo":         "studio",
+	}, &q)
+	if box := q.Inventory[0].Boxes[0]; ids[box.ID] {
+		t.Errorf("box %s shared by two profiles", box.ID)
+	}
+}
+
+func TestSaveTemplate(t *testing.T) {
+	s := newTestServer(t)
+	c := admin(t, s)
+	template := func(item map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"nome": "Kitnet", "inventario": []map[string]interface{}{{
+			"nome": "sala", "caixas": []map[string]interface{}{{"etiqueta": "tv", "items": []map[string]interface{}{item}}},
+		}}}
+	}
+	c.must(http.StatusOK, "PUT", "/admin/templates/kitnet", template(map[string]interface{}{"catalogo": "tv", "quantidade": 1}), nil)
+	c.must(http.StatusBadRequest, "PUT", "/admin/templates/kitnet", template(map[string]interface{}{"catalogo": "piano", "quantidade": 1}), nil)
+	c.must(http.StatusBadRequest, "PUT", "/admin/templates/kitnet", template(map[string]interface{}{"tipo": "tv", "quantidade": 1}), nil)
+}
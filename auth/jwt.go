@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("Invalid or expired session token")
+
+// header is the only JWT header accepted, HS256 signed
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims are the JWT claims of a session, the subject
+// being the phone number the user verified
+type Claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Issuer signs and verifies session tokens
+type Issuer struct {
+	key []byte
+	TTL time.Duration
+}
+
+func NewIssuer(key []byte, ttl time.Duration) *Issuer {
+	return &Issuer{key: key, TTL: ttl}
+}
+
+// Issue returns a signed token for the phone number
+func (i *Issuer) Issue(phone string) (token string, claims Claims, err error) {
+	now := time.Now()
+	claims = Claims{Subject: phone, IssuedAt: now.Unix(), ExpiresAt: now.Add(i.TTL).Unix()}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	token = unsigned + "." + base64.RawURLEncoding.EncodeToString(i.sign(unsigned))
+	return
+}
+
+// Parse verifies the token signature and expiry
+func (i *Issuer) Parse(token string) (claims Claims, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return claims, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, i.sign(parts[0]+"."+parts[1])) {
+		return claims, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return claims, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return claims, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (i *Issuer) sign(unsigned string) []byte {
+	m := hmac.New(sha256.New, i.key)
+	m.Write([]byte(unsigned))
+	return m.Sum(nil)
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssuerParse(t *testing.T) {
+	issuer := NewIssuer([]byte("secret"), time.Hour)
+	token, _, err := issuer.Issue("+5521999999999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _, err := NewIssuer([]byte("secret"), -time.Minute).Issue("+5521999999999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := NewIssuer([]byte("other"), time.Hour).Issue("+5521999999999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"+5521888888888","exp":9999999999}`)) + "." + parts[2]
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + "."
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{name: "valid", token: token},
+		{name: "expired", token: expired, err: ErrInvalidToken},
+		{name: "other key", token: other, err: ErrInvalidToken},
+		{name: "forged claims", token: forged, err: ErrInvalidToken},
+		{name: "alg none", token: none, err: ErrInvalidToken},
+		{name: "two parts", token: parts[0] + "." + parts[1], err: ErrInvalidToken},
+		{name: "empty", err: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := issuer.Parse(tt.token)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err == nil && claims.Subject != "+5521999999999" {
+				t.Errorf("subject = %q", claims.Subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("Phone number must have the area code and 8 or 9 digits")
+
+// NewCode returns a random 6 digit one time password
+func NewCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// HashCode keys an HMAC of the code and its phone number with a
+// server secret. A plain hash of a 6 digit code is reversed by
+// trying the million codes, the HMAC is not without the key
+func HashCode(key []byte, phone, code string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(phone + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckCode compares a code with its stored hash in constant time
+func CheckCode(key []byte, phone, code, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashCode(key, phone, code)), []byte(hash)) == 1
+}
+
+// NormalizePhone turns a Brazilian phone number into E.164,
+// adding the country code when it is missing
+func NormalizePhone(phone string) (string, error) {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	if len(d) == 10 || len(d) == 11 {
+		d = "55" + d
+	}
+	if !strings.HasPrefix(d, "55") || len(d) < 12 || len(d) > 13 {
+		return "", ErrInvalidPhone
+	}
+	return "+" + d, nil
+}
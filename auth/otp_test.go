@@ -0,0 +1,67 @@
+package auth
+
+import "testing"
+
+func TestCheckCode(t *testing.T) {
+	key := []byte("secret")
+	hash := HashCode(key, "+5521999999999", "123456")
+	tests := []struct {
+		name  string
+		key   []byte
+		phone string
+		code  string
+		want  bool
+	}{
+		{name: "right code", key: key, phone: "+5521999999999", code: "123456", want: true},
+		{name: "wrong code", key: key, phone: "+5521999999999", code: "123457"},
+		{name: "other phone", key: key, phone: "+5521888888888", code: "123456"},
+		{name: "other key", key: []byte("other"), phone: "+5521999999999", code: "123456"},
+		{name: "empty code", key: key, phone: "+5521999999999"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckCode(tt.key, tt.phone, tt.code, hash); got != tt.want {
+				t.Errorf("CheckCode = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := NewCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 6 {
+			t.Fatalf("code %q is not 6 digits", code)
+		}
+		for _, r := range code {
+			if r < '0' || r > '9' {
+				t.Fatalf("code %q is not 6 digits", code)
+			}
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+		err   error
+	}{
+		{"+55 21 99999-9999", "+5521999999999", nil},
+		{"(21) 99999-9999", "+5521999999999", nil},
+		{"21 3333-4444", "+552133334444", nil},
+		{"5521999999999", "+5521999999999", nil},
+		{"99999-9999", "", ErrInvalidPhone},
+		{"+44 20 7946 0958", "", ErrInvalidPhone},
+		{"", "", ErrInvalidPhone},
+	}
+	for _, tt := range tests {
+		got, err := NormalizePhone(tt.phone)
+		if got != tt.want || err != tt.err {
+			t.Errorf("NormalizePhone(%q) = %q, %v, want %q, %v", tt.phone, got, err, tt.want, tt.err)
+		}
+	}
+}
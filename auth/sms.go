@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// SMSSender delivers text messages to a phone number
+type SMSSender interface {
+	Send(phone, message string) error
+}
+
+// LogSender only logs the messages, for local development
+type LogSender struct{}
+
+func (LogSender) Send(phone, message string) error {
+	log.Printf("SMS to %s: %s", phone, message)
+	return nil
+}
+
+// SMSFromEnv picks the sender named by SMS_PROVIDER, "log" by default
+func SMSFromEnv() (SMSSender, error) {
+	switch name := os.Getenv("SMS_PROVIDER"); name {
+	case "", "log":
+		return LogSender{}, nil
+	default:
+		return nil, fmt.Errorf("Unknown SMS provider %q", name)
+	}
+}
@@ -0,0 +1,148 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+var (
+	ErrCEPNotFound     = errors.New("CEP not found")
+	ErrAddressNotFound = errors.New("Address could not be located")
+)
+
+// CEPLookup finds the street, neighbourhood, city
+// and state of a Brazilian postal code
+type CEPLookup interface {
+	LookupCEP(cep string) (models.Address, error)
+}
+
+// Geocoder finds the coordinates of an address
+type Geocoder interface {
+	Geocode(a models.Address) (lat, lng float64, err error)
+}
+
+// Resolver completes the addresses users type in, so the
+// distance is calculated from the right coordinates
+type Resolver struct {
+	CEP      CEPLookup
+	Geocoder Geocoder
+}
+
+// Resolve normalises the address, takes street, neighbourhood,
+// city and state from its CEP and, when it has no coordinates,
+// geocodes it. A CEP lookup failing for other reasons than an
+// unknown CEP keeps the address as typed
+func (r *Resolver) Resolve(a *models.Address) error {
+	a.Normalize()
+	if a.CEP != "" && r.CEP != nil {
+		found, err := r.CEP.LookupCEP(models.CEPDigits(a.CEP))
+		if err == ErrCEPNotFound {
+			return err
+		} else if err != nil {
+			log.Printf("CEP lookup %T failed: %v", r.CEP, err)
+		} else {
+			merge(a, found)
+		}
+	}
+	if a.Latitude != 0 || a.Longitude != 0 || r.Geocoder == nil {
+		return nil
+	}
+	lat, lng, err := r.Geocoder.Geocode(*a)
+	if err != nil {
+		return err
+	}
+	a.Latitude, a.Longitude = lat, lng
+	return nil
+}
+
+// merge overrides the address with the non empty fields found by CEP
+func merge(a *models.Address, found models.Address) {
+	found.Normalize()
+	if found.Street != "" {
+		a.Street = found.Street
+	}
+	if found.Neighbourhood != "" {
+		a.Neighbourhood = found.Neighbourhood
+	}
+	if found.City != "" {
+		a.City = found.City
+	}
+	if found.State != "" {
+		a.State = found.State
+	}
+	if a.Latitude == 0 && a.Longitude == 0 {
+		a.Latitude, a.Longitude = found.Latitude, found.Longitude
+	}
+}
+
+// query writes the address the way geocoders expect it
+func query(a models.Address) string {
+	parts := []string{a.Street}
+	if a.Number > 0 {
+		parts[0] = fmt.Sprintf("%s, %d", a.Street, a.Number)
+	}
+	for _, part := range []string{a.Neighbourhood, a.City, a.State, a.CEP} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(append(parts, "Brasil"), ", ")
+}
+
+// ResolverFromEnv picks the CEP lookup in CEP_PROVIDER ("viacep"
+// by default) and the geocoder in GEOCODER ("google" by default
+// when MAPS_KEY is set, none otherwise, leaving addresses without
+// coordinates). Both accept "fixture", which answers from the
+// addresses in the GEO_FIXTURES file, or from a few built-in ones,
+// without network
+func ResolverFromEnv() (*Resolver, error) {
+	var fixture Fixture
+	load := func() (err error) {
+		if fixture != nil {
+			return
+		}
+		if path := os.Getenv("GEO_FIXTURES"); path != "" {
+			fixture, err = LoadFixture(path)
+			return
+		}
+		fixture = DefaultFixture()
+		return
+	}
+	r := &Resolver{}
+	switch name := os.Getenv("CEP_PROVIDER"); name {
+	case "", "viacep":
+		r.CEP = NewViaCEP()
+	case "fixture":
+		if err := load(); err != nil {
+			return nil, err
+		}
+		r.CEP = fixture
+	default:
+		return nil, fmt.Errorf("Unknown CEP provider %q", name)
+	}
+	key := os.Getenv("MAPS_KEY")
+	switch name := os.Getenv("GEOCODER"); name {
+	case "":
+		if key != "" {
+			r.Geocoder = NewGoogle(key)
+		}
+	case "google":
+		if key == "" {
+			return nil, errors.New("GEOCODER google requires MAPS_KEY")
+		}
+		r.Geocoder = NewGoogle(key)
+	case "fixture":
+		if err := load(); err != nil {
+			return nil, err
+		}
+		r.Geocoder = fixture
+	default:
+		return nil, fmt.Errorf("Unknown geocoder %q", name)
+	}
+	return r, nil
+}
@@ -0,0 +1,121 @@
+package geo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+// brokenCEP fails every lookup as if the service were down
+type brokenCEP struct{}
+
+func (brokenCEP) LookupCEP(cep string) (models.Address, error) {
+	return models.Address{}, errors.New("service unavailable")
+}
+
+func TestResolve(t *testing.T) {
+	fixture := DefaultFixture()
+	tests := []struct {
+		name     string
+		resolver Resolver
+		address  models.Address
+		want     models.Address
+		err      error
+	}{
+		{
+			name:     "completed from the CEP",
+			resolver: Resolver{CEP: fixture, Geocoder: fixture},
+			address:  models.Address{Number: 259, CEP: "20271160"},
+			want: models.Address{Street: "Rua Santa Luiza", Number: 259, Neighbourhood: "Maracanã", City: "Rio de Janeiro",
+				State: "RJ", CEP: "20271-160", Latitude: -22.9163398, Longitude: -43.2341546},
+		},
+		{
+			name:     "coordinates typed are kept",
+			resolver: Resolver{CEP: fixture, Geocoder: fixture},
+			address:  models.Address{Street: "Paulista", CEP: "01310-100", Latitude: -23.56, Longitude: -46.65},
+			want: models.Address{Street: "Avenida Paulista", Neighbourhood: "Bela Vista", City: "São Paulo",
+				State: "SP", CEP: "01310-100", Latitude: -23.56, Longitude: -46.65},
+		},
+		{
+			name:     "unknown CEP",
+			resolver: Resolver{CEP: fixture, Geocoder: fixture},
+			address:  models.Address{Street: "Rua Nova", CEP: "99999-999"},
+			err:      ErrCEPNotFound,
+		},
+		{
+			name:     "CEP service down keeps the address typed",
+			resolver: Resolver{CEP: brokenCEP{}, Geocoder: fixture},
+			address:  models.Address{Street: " Avenida Atlântica ", City: "Rio de Janeiro", State: "rj"},
+			want: models.Address{Street: "Avenida Atlântica", City: "Rio de Janeiro", State: "RJ",
+				Latitude: -22.9691, Longitude: -43.1806},
+		},
+		{
+			name:     "not located",
+			resolver: Resolver{Geocoder: fixture},
+			address:  models.Address{Street: "Rua Nova", City: "Niterói"},
+			err:      ErrAddressNotFound,
+		},
+		{
+			name:     "without providers",
+			resolver: Resolver{},
+			address:  models.Address{Street: "Rua Nova", CEP: "99999999"},
+			want:     models.Address{Street: "Rua Nova", CEP: "99999-999"},
+		},
+	}
+	for _, tt := range tests {
+		a := tt.address
+		err := tt.resolver.Resolve(&a)
+		if err != tt.err {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		} else if err == nil && a != tt.want {
+			t.Errorf("%s: address %+v, want %+v", tt.name, a, tt.want)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	a := models.Address{Street: "Rua Santa Luiza", Number: 259, City: "Rio de Janeiro", State: "RJ", CEP: "20271-160"}
+	if got, want := query(a), "Rua Santa Luiza, 259, Rio de Janeiro, RJ, 20271-160, Brasil"; got != want {
+		t.Errorf("query = %q, want %q", got, want)
+	}
+}
+
+func TestResolverFromEnv(t *testing.T) {
+	t.Setenv("CEP_PROVIDER", "fixture")
+	t.Setenv("GEOCODER", "fixture")
+	t.Setenv("GEO_FIXTURES", "")
+	r, err := ResolverFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.CEP.(Fixture); !ok {
+		t.Errorf("CEP lookup %T", r.CEP)
+	}
+	t.Setenv("GEOCODER", "nominatim")
+	if _, err = ResolverFromEnv(); err == nil {
+		t.Error("unknown geocoder accepted")
+	}
+
+	// google only by default when there is a key
+	t.Setenv("MAPS_KEY", "")
+	t.Setenv("GEOCODER", "")
+	if r, err = ResolverFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Geocoder != nil {
+		t.Errorf("geocoder %T without key", r.Geocoder)
+	}
+	t.Setenv("GEOCODER", "google")
+	if _, err = ResolverFromEnv(); err == nil {
+		t.Error("google geocoder accepted without key")
+	}
+	t.Setenv("MAPS_KEY", "key")
+	t.Setenv("GEOCODER", "")
+	if r, err = ResolverFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Geocoder.(*Google); !ok {
+		t.Errorf("geocoder %T with key", r.Geocoder)
+	}
+}
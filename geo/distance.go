@@ -0,0 +1,83 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+// DistanceProvider returns the road distance in km between two addresses
+type DistanceProvider interface {
+	Distance(from, to models.Address) (float64, error)
+}
+
+// RouteDistance adds up the distance of every leg of the route
+func RouteDistance(d DistanceProvider, route []models.Address) (total float64, err error) {
+	for i := 1; i < len(route); i++ {
+		leg, err := d.Distance(route[i-1], route[i])
+		if err != nil {
+			return 0, err
+		}
+		total += leg
+	}
+	return
+}
+
+// Fallback asks each provider in order and returns the first
+// distance obtained, so a quote can still be made offline
+type Fallback []DistanceProvider
+
+func (f Fallback) Distance(from, to models.Address) (dist float64, err error) {
+	if len(f) == 0 {
+		return dist, errors.New("No distance provider configured")
+	}
+	for _, provider := range f {
+		if dist, err = provider.Distance(from, to); err == nil {
+			return
+		}
+		log.Printf("distance provider %T failed: %v", provider, err)
+	}
+	return
+}
+
+// DistanceFromEnv builds the fallback chain listed in DISTANCE_PROVIDERS
+// (comma separated, "google,haversine" by default, or only "haversine"
+// when MAPS_KEY is not set). The haversine road factor can be tuned
+// with ROAD_FACTOR
+func DistanceFromEnv() (Fallback, error) {
+	key := os.Getenv("MAPS_KEY")
+	names := os.Getenv("DISTANCE_PROVIDERS")
+	if names == "" && key == "" {
+		names = "haversine"
+	} else if names == "" {
+		names = "google,haversine"
+	}
+	var chain Fallback
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "google":
+			if key == "" {
+				return nil, errors.New("Distance provider google requires MAPS_KEY")
+			}
+			chain = append(chain, NewGoogle(key))
+		case "haversine":
+			h := NewHaversine()
+			if factor := os.Getenv("ROAD_FACTOR"); factor != "" {
+				f, err := strconv.ParseFloat(factor, 64)
+				if err != nil {
+					return nil, err
+				}
+				h.RoadFactor = f
+			}
+			chain = append(chain, h)
+		default:
+			return nil, fmt.Errorf("Unknown distance provider %q", name)
+		}
+	}
+	return chain, nil
+}
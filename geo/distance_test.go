@@ -0,0 +1,112 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+// fixed answers the same distance, or fails, and counts calls
+type fixed struct {
+	km    float64
+	err   error
+	calls int
+}
+
+func (f *fixed) Distance(from, to models.Address) (float64, error) {
+	f.calls++
+	return f.km, f.err
+}
+
+func TestHaversine(t *testing.T) {
+	h := &Haversine{RoadFactor: 1}
+	tests := []struct {
+		name     string
+		from, to models.Address
+		km       float64
+	}{
+		{name: "a degree of the equator", from: models.Address{Longitude: 1}, to: models.Address{Longitude: 2}, km: 111.19},
+		{name: "a degree of a meridian", from: models.Address{Latitude: 1}, to: models.Address{Latitude: 2}, km: 111.19},
+		{name: "Rio to São Paulo",
+			from: models.Address{Latitude: -22.9163398, Longitude: -43.2341546},
+			to:   models.Address{Latitude: -23.5604276, Longitude: -46.6579269}, km: 356.6},
+	}
+	for _, tt := range tests {
+		km, err := h.Distance(tt.from, tt.to)
+		if err != nil || math.Abs(km-tt.km) > 0.5 {
+			t.Errorf("%s: %v km, %v, want %v", tt.name, km, err, tt.km)
+		}
+	}
+	rio := models.Address{Latitude: -22.9, Longitude: -43.2}
+	if _, err := h.Distance(rio, models.Address{}); err == nil {
+		t.Error("distance to an address without coordinates")
+	}
+	straight, _ := h.Distance(rio, models.Address{Latitude: -23, Longitude: -43.5})
+	road, _ := NewHaversine().Distance(rio, models.Address{Latitude: -23, Longitude: -43.5})
+	if math.Abs(road-straight*1.3) > 1e-9 {
+		t.Errorf("road distance %v, straight %v", road, straight)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	failing := &fixed{err: errors.New("quota exceeded")}
+	working := &fixed{km: 12}
+	unused := &fixed{km: 99}
+	km, err := Fallback{failing, working, unused}.Distance(models.Address{}, models.Address{})
+	if err != nil || km != 12 {
+		t.Errorf("distance %v, %v, want 12", km, err)
+	}
+	if failing.calls != 1 || working.calls != 1 || unused.calls != 0 {
+		t.Errorf("calls %d, %d, %d", failing.calls, working.calls, unused.calls)
+	}
+	if _, err = (Fallback{failing}).Distance(models.Address{}, models.Address{}); err != failing.err {
+		t.Errorf("error %v, want the one of the last provider", err)
+	}
+	if _, err = (Fallback{}).Distance(models.Address{}, models.Address{}); err == nil {
+		t.Error("distance without providers")
+	}
+}
+
+func TestRouteDistance(t *testing.T) {
+	d := &fixed{km: 5}
+	route := make([]models.Address, 4)
+	if km, err := RouteDistance(d, route); err != nil || km != 15 || d.calls != 3 {
+		t.Errorf("distance %v, %v after %d legs", km, err, d.calls)
+	}
+	if km, err := RouteDistance(d, route[:1]); err != nil || km != 0 {
+		t.Errorf("distance of a single address %v, %v", km, err)
+	}
+	d.err = errors.New("unavailable")
+	if _, err := RouteDistance(d, route); err != d.err {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestDistanceFromEnv(t *testing.T) {
+	tests := []struct {
+		providers string
+		key       string
+		factor    string
+		chain     int
+		valid     bool
+	}{
+		{key: "key", chain: 2, valid: true},
+		{chain: 1, valid: true},
+		{providers: "haversine", factor: "1.5", chain: 1, valid: true},
+		{providers: "google, haversine", key: "key", chain: 2, valid: true},
+		{providers: "google, haversine"},
+		{providers: "haversine", factor: "x"},
+		{providers: "osrm"},
+	}
+	for _, tt := range tests {
+		t.Setenv("DISTANCE_PROVIDERS", tt.providers)
+		t.Setenv("MAPS_KEY", tt.key)
+		t.Setenv("ROAD_FACTOR", tt.factor)
+		chain, err := DistanceFromEnv()
+		if (err == nil) != tt.valid || len(chain) != tt.chain {
+			t.Errorf("%q: %d providers, %v", tt.providers, len(chain), err)
+		}
+	}
+}
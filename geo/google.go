@@ -0,0 +1,106 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+const (
+	distanceMatrixEndpoint = "https://maps.googleapis.com/maps/api/distancematrix/json"
+	geocodeEndpoint        = "https://maps.googleapis.com/maps/api/geocode/json"
+)
+
+// Google asks the Google Distance Matrix API for the driving
+// distance and the Geocoding API for coordinates
+type Google struct {
+	Key    string
+	Client *http.Client
+}
+
+func NewGoogle(key string) *Google {
+	return &Google{Key: key, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (g *Google) Distance(from, to models.Address) (dist float64, err error) {
+	if g.Key == "" {
+		return dist, errors.New("MAPS_KEY is not set")
+	}
+	params := url.Values{}
+	params.Set("origins", fmt.Sprintf("%v,%v", from.Latitude, from.Longitude))
+	params.Set("destinations", fmt.Sprintf("%v,%v", to.Latitude, to.Longitude))
+	params.Set("key", g.Key)
+	resp, err := g.Client.Get(distanceMatrixEndpoint + "?" + params.Encode())
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return dist, errors.New(fmt.Sprintf("Google responded with wrong status code: %v", resp.StatusCode))
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	var mapsResp models.MapsResponse
+	if err = json.Unmarshal(body, &mapsResp); err != nil {
+		return
+	}
+	if mapsResp.Status != "OK" || len(mapsResp.Rows) == 0 || len(mapsResp.Rows[0].Elements) == 0 {
+		return dist, errors.New(fmt.Sprintf("Google could not calculate the distance: %s", mapsResp.Status))
+	}
+	element := mapsResp.Rows[0].Elements[0]
+	if element.Status != "OK" {
+		return dist, errors.New(fmt.Sprintf("Google could not calculate the distance: %s", element.Status))
+	}
+	dist = float64(element.Distance.Value) / 1000
+	return
+}
+
+type geocodeResponse struct {
+	Status  string `json:"status"`
+	Results []struct {
+		Geometry struct {
+			Location struct {
+				Lat float64 `json:"lat"`
+				Lng float64 `json:"lng"`
+			} `json:"location"`
+		} `json:"geometry"`
+	} `json:"results"`
+}
+
+func (g *Google) Geocode(a models.Address) (lat, lng float64, err error) {
+	if g.Key == "" {
+		return lat, lng, errors.New("MAPS_KEY is not set")
+	}
+	params := url.Values{}
+	params.Set("address", query(a))
+	params.Set("region", "br")
+	params.Set("key", g.Key)
+	resp, err := g.Client.Get(geocodeEndpoint + "?" + params.Encode())
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return lat, lng, fmt.Errorf("Google responded with wrong status code: %v", resp.StatusCode)
+	}
+	var body geocodeResponse
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return
+	}
+	if body.Status == "ZERO_RESULTS" {
+		return lat, lng, ErrAddressNotFound
+	}
+	if body.Status != "OK" || len(body.Results) == 0 {
+		return lat, lng, fmt.Errorf("Google could not geocode the address: %s", body.Status)
+	}
+	location := body.Results[0].Geometry.Location
+	return location.Lat, location.Lng, nil
+}
@@ -0,0 +1,41 @@
+package geo
+
+import (
+	"errors"
+	"math"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+const earthRadius = 6371.0
+
+// Haversine estimates the road distance from the great-circle
+// distance between the coordinates, multiplied by RoadFactor
+// to account for streets not being straight lines
+type Haversine struct {
+	RoadFactor float64
+}
+
+func NewHaversine() *Haversine {
+	return &Haversine{RoadFactor: 1.3}
+}
+
+func (h *Haversine) Distance(from, to models.Address) (float64, error) {
+	if !hasCoordinates(from) || !hasCoordinates(to) {
+		return 0, errors.New("Address has no coordinates")
+	}
+	lat1, lat2 := radians(from.Latitude), radians(to.Latitude)
+	dLat := lat2 - lat1
+	dLng := radians(to.Longitude - from.Longitude)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return earthRadius * c * h.RoadFactor, nil
+}
+
+func hasCoordinates(a models.Address) bool {
+	return a.Latitude != 0 || a.Longitude != 0
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
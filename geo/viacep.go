@@ -0,0 +1,58 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/models"
+)
+
+const viaCEPEndpoint = "https://viacep.com.br/ws/%s/json/"
+
+// ViaCEP looks CEPs up in the ViaCEP web service
+type ViaCEP struct {
+	Client *http.Client
+}
+
+func NewViaCEP() *ViaCEP {
+	return &ViaCEP{Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type viaCEPResponse struct {
+	CEP           string `json:"cep"`
+	Street        string `json:"logradouro"`
+	Neighbourhood string `json:"bairro"`
+	City          string `json:"localidade"`
+	State         string `json:"uf"`
+	Error         bool   `json:"erro"`
+}
+
+func (v *ViaCEP) LookupCEP(cep string) (a models.Address, err error) {
+	resp, err := v.Client.Get(fmt.Sprintf(viaCEPEndpoint, cep))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		return a, ErrCEPNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return a, fmt.Errorf("ViaCEP responded with wrong status code: %v", resp.StatusCode)
+	}
+	var body viaCEPResponse
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return
+	}
+	if body.Error {
+		return a, ErrCEPNotFound
+	}
+	return models.Address{
+		Street:        body.Street,
+		Neighbourhood: body.Neighbourhood,
+		City:          body.City,
+		State:         body.State,
+		CEP:           body.CEP,
+	}, nil
+}
@@ -0,0 +1,102 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+)
+
+// document is a minimal PDF writer: A4 pages drawn with
+// filled rectangles and the standard Helvetica fonts, which
+// every PDF reader ships so nothing has to be embedded
+type document struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+}
+
+func (d *document) addPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+}
+
+// rect fills a rectangle whose top left corner is at x, y
+// measured from the top left of the page
+func (d *document) rect(x, y, w, h float64) {
+	fmt.Fprintf(d.page, "%.2f %.2f %.2f %.2f re f\n", x, pageHeight-y-h, w, h)
+}
+
+// strokeRect draws a dashed rectangle outline, used as cutting guide
+func (d *document) strokeRect(x, y, w, h float64) {
+	fmt.Fprintf(d.page, "q 0.6 G 0.5 w [3 3] 0 d %.2f %.2f %.2f %.2f re S Q\n", x, pageHeight-y-h, w, h)
+}
+
+// text writes s with its baseline at x, y
+func (d *document) text(x, y float64, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, pageHeight-y, escape(s))
+}
+
+// bytes serializes the document
+func (d *document) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	out.WriteString("%PDF-1.4\n")
+	// objects 1 to 4 are fixed, then a page and its contents for every page
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// escape encodes s as WinAnsi, which matches Latin-1 for the
+// accented letters of Portuguese, and escapes string delimiters.
+// Characters WinAnsi lacks are replaced by a question mark
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		case r == '…':
+			b.WriteByte(0x85)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
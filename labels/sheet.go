@@ -0,0 +1,98 @@
+package labels
+
+import (
+	"fmt"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Label is what gets printed on a box
+type Label struct {
+	URL     string
+	Room    string
+	Number  int
+	Title   string
+	Fragile bool
+}
+
+// The sheet is a grid of 2 columns and 4 rows of labels
+const (
+	columns     = 2
+	rows        = 4
+	perPage     = columns * rows
+	labelWidth  = pageWidth / columns
+	labelHeight = pageHeight / rows
+	margin      = 14.0
+	qrSize      = 150.0
+)
+
+// Sheet renders the labels as an A4 PDF, as many pages as needed
+func Sheet(labels []Label) ([]byte, error) {
+	d := &document{}
+	if len(labels) == 0 {
+		d.addPage()
+	}
+	for i, label := range labels {
+		if i%perPage == 0 {
+			d.addPage()
+		}
+		x := float64(i%columns) * labelWidth
+		y := float64(i%perPage/columns) * labelHeight
+		if err := drawLabel(d, x, y, label); err != nil {
+			return nil, err
+		}
+	}
+	return d.bytes(), nil
+}
+
+func drawLabel(d *document, x, y float64, label Label) error {
+	d.strokeRect(x+4, y+4, labelWidth-8, labelHeight-8)
+	qrY := y + (labelHeight-qrSize)/2
+	if err := drawQR(d, x+margin, qrY, qrSize, label.URL); err != nil {
+		return err
+	}
+	textX := x + margin + qrSize + 8
+	textY := qrY + 22
+	d.text(textX, textY, 13, true, truncate(label.Room, 15))
+	d.text(textX, textY+34, 28, true, fmt.Sprintf("#%d", label.Number))
+	d.text(textX, textY+56, 9, false, truncate(label.Title, 22))
+	if label.Fragile {
+		d.rect(textX, textY+80, 96, 26)
+		fmt.Fprint(d.page, "1 g\n")
+		d.text(textX+10, textY+99, 16, true, "FRÁGIL")
+		fmt.Fprint(d.page, "0 g\n")
+	}
+	return nil
+}
+
+// drawQR draws the QR code modules as rectangles, merging
+// the dark modules of a row into runs to keep the file small
+func drawQR(d *document, x, y, size float64, content string) error {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return err
+	}
+	bitmap := q.Bitmap()
+	module := size / float64(len(bitmap))
+	for row, line := range bitmap {
+		for col := 0; col < len(line); col++ {
+			if !line[col] {
+				continue
+			}
+			start := col
+			for col < len(line) && line[col] {
+				col++
+			}
+			d.rect(x+float64(start)*module, y+float64(row)*module, float64(col-start)*module, module)
+		}
+	}
+	return nil
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
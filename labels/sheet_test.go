@@ -0,0 +1,85 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestSheet(t *testing.T) {
+	tests := []struct {
+		labels int
+		pages  int
+	}{
+		{labels: 0, pages: 1},
+		{labels: 1, pages: 1},
+		{labels: 8, pages: 1},
+		{labels: 9, pages: 2},
+		{labels: 17, pages: 3},
+	}
+	for _, tt := range tests {
+		labels := make([]Label, tt.labels)
+		for i := range labels {
+			labels[i] = Label{URL: fmt.Sprintf("https://mudae.herokuapp.com/b/%d", i), Room: "sala", Number: i + 1, Title: "livros", Fragile: i%2 == 0}
+		}
+		pdf, err := Sheet(labels)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+			t.Errorf("%d labels: not a PDF", tt.labels)
+		}
+		if n := bytes.Count(pdf, []byte("/Type /Page ")); n != tt.pages {
+			t.Errorf("%d labels: %d pages, want %d", tt.labels, n, tt.pages)
+		}
+		checkXref(t, pdf)
+	}
+}
+
+// checkXref tells readers find every object where the
+// cross-reference table says it is
+func checkXref(t *testing.T, pdf []byte) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	start, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[start:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", start)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[start:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("object %d is not at %d", i+1, offset)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := map[string]string{
+		"sala":      "sala",
+		"(frágil)":  "\\(fr\xe1gil\\)",
+		`a\b`:       `a\\b`,
+		"coração\n": "cora\xe7\xe3o ",
+		"mudança ✓": "mudan\xe7a ?",
+		"área…":     "\xe1rea\x85",
+	}
+	for in, want := range tests {
+		if got := escape(in); got != want {
+			t.Errorf("escape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("Cozinha e área de serviço", 15); got != "Cozinha e área…" {
+		t.Errorf("truncate = %q", got)
+	}
+	if got := truncate("Cozinha", 15); got != "Cozinha" {
+		t.Errorf("truncate = %q", got)
+	}
+}
@@ -0,0 +1,51 @@
+package labels
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidToken = errors.New("Invalid box token")
+
+// signatureSize is how many bytes of the HMAC go in the token,
+// enough to be unguessable while keeping the QR code small
+const signatureSize = 16
+
+// Signer creates and checks the box tokens encoded in QR codes.
+// A token is the box ID followed by its HMAC-SHA256, so it does
+// not expose the user number and cannot be forged without the key
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{key: key}
+}
+
+// Sign returns the token of a box
+func (s *Signer) Sign(boxID string) string {
+	return boxID + "." + base64.RawURLEncoding.EncodeToString(s.mac(boxID))
+}
+
+// Verify checks the token signature and returns the box ID
+func (s *Signer) Verify(token string) (string, error) {
+	dot := strings.LastIndex(token, ".")
+	if dot <= 0 {
+		return "", ErrInvalidToken
+	}
+	boxID := token[:dot]
+	signature, err := base64.RawURLEncoding.DecodeString(token[dot+1:])
+	if err != nil || !hmac.Equal(signature, s.mac(boxID)) {
+		return "", ErrInvalidToken
+	}
+	return boxID, nil
+}
+
+func (s *Signer) mac(boxID string) []byte {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(boxID))
+	return m.Sum(nil)[:signatureSize]
+}
@@ -0,0 +1,46 @@
+package labels
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSignerVerify(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+	token := s.Sign("5c1d2e3f")
+	tests := []struct {
+		name  string
+		token string
+		want  string
+		err   error
+	}{
+		{name: "signed", token: token, want: "5c1d2e3f"},
+		{name: "box id with dots", token: s.Sign("a.b"), want: "a.b"},
+		{name: "other box", token: "5c1d2e40" + token[strings.LastIndex(token, "."):], err: ErrInvalidToken},
+		{name: "other key", token: NewSigner([]byte("other")).Sign("5c1d2e3f"), err: ErrInvalidToken},
+		{name: "truncated signature", token: token[:len(token)-2], err: ErrInvalidToken},
+		{name: "not base64", token: "5c1d2e3f.!!", err: ErrInvalidToken},
+		{name: "no signature", token: "5c1d2e3f", err: ErrInvalidToken},
+		{name: "no box id", token: token[strings.LastIndex(token, "."):], err: ErrInvalidToken},
+		{name: "empty", err: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Verify(tt.token)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("box = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignIsShort(t *testing.T) {
+	token := NewSigner([]byte("secret")).Sign("5c1d2e3f")
+	// 16 bytes of HMAC are 22 base64 characters
+	if got, want := len(token), len("5c1d2e3f")+1+22; got != want {
+		t.Errorf("token %s has %d characters, want %d", token, got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// CancellationRule keeps a fee, as a percentage of what was
+// paid, when the move is cancelled at least HoursBefore it starts
+type CancellationRule struct {
+	HoursBefore float64 `bson:"hours_before" json:"horas_antes"`
+	Fee         float64 `bson:"fee" json:"multa_percentual"`
+}
+
+// CancellationPolicy is the set of rules of a tariff. The rule
+// with the most hours still before the move applies and, once no
+// rule does, nothing is refunded. Without rules all is refunded
+type CancellationPolicy []CancellationRule
+
+// RefundStatus follows the refund of a cancelled offer,
+// or of a charge no offer took, at the payment provider
+type RefundStatus string
+
+const (
+	// RefundPending is still to be asked to the provider
+	RefundPending RefundStatus = "pending"
+	// RefundRequested was asked to the provider, and accepted
+	// once it has a RefundID. Without one the outcome is unknown,
+	// so it is not due again
+	RefundRequested RefundStatus = "requested"
+	// RefundFailed was refused by the provider and must be made again
+	RefundFailed RefundStatus = "failed"
+	// RefundDone was confirmed by the provider
+	RefundDone RefundStatus = "refunded"
+)
+
+// Cancellation records why and when an offer was cancelled
+// and how much of what was paid goes back to the customer
+type Cancellation struct {
+	Reason       string       `bson:"reason,omitempty" json:"motivo,omitempty"`
+	At           time.Time    `bson:"at" json:"em"`
+	HoursBefore  float64      `bson:"hours_before" json:"horas_antes"`
+	Fee          float64      `bson:"fee" json:"multa_percentual"`
+	Paid         float64      `bson:"paid" json:"valor_pago"`
+	Refund       float64      `bson:"refund" json:"reembolso"`
+	RefundStatus RefundStatus `bson:"refund_status,omitempty" json:"reembolso_status,omitempty"`
+	RefundID     string       `bson:"refund_id,omitempty" json:"reembolso_id,omitempty"`
+	// RefundError is why the provider refused the refund
+	RefundError string `bson:"refund_error,omitempty" json:"reembolso_erro,omitempty"`
+}
+
+// RefundDue tells whether the refund is still to be made.
+// Cancellations recorded before refund statuses have none
+// and are due as long as they have no refund ID
+func (c *Cancellation) RefundDue() bool {
+	switch c.RefundStatus {
+	case RefundPending, RefundFailed:
+		return c.Refund > 0
+	case "":
+		return c.Refund > 0 && c.RefundID == ""
+	}
+	return false
+}
+
+// Validate checks fees are percentages and each
+// rule starts at a different time
+func (cp CancellationPolicy) Validate() error {
+	seen := make(map[float64]bool)
+	for _, rule := range cp {
+		if rule.HoursBefore < 0 {
+			return errors.New("Cancellation rules cannot start after the move")
+		}
+		if rule.Fee < 0 || rule.Fee > 100 {
+			return errors.New("Cancellation fees must be a percentage from 0 to 100")
+		}
+		if seen[rule.HoursBefore] {
+			return errors.New("Cancellation rules must start at different times")
+		}
+		seen[rule.HoursBefore] = true
+	}
+	return nil
+}
+
+// Fee is the percentage kept when cancelling hours before the move
+func (cp CancellationPolicy) Fee(hours float64) float64 {
+	if len(cp) == 0 {
+		return 0
+	}
+	rules := append(CancellationPolicy(nil), cp...)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].HoursBefore > rules[j].HoursBefore })
+	for _, rule := range rules {
+		if hours >= rule.HoursBefore {
+			return rule.Fee
+		}
+	}
+	return 100
+}
+
+// Cancellation works out what cancelling the offer at the
+// given time refunds. Only paid offers have something to refund
+func (p *Profile) Cancellation(policy CancellationPolicy, reason string, at time.Time) Cancellation {
+	start := p.MovingTime
+	if start.IsZero() {
+		start = p.MovingData
+	}
+	c := Cancellation{Reason: reason, At: at, HoursBefore: roundCents(start.Sub(at).Hours())}
+	c.Fee = policy.Fee(c.HoursBefore)
+	if p.Offer.CurrentStatus() == OfferPaid {
+		c.Paid = p.Offer.TotalValue
+		c.Refund = roundCents(c.Paid * (100 - c.Fee) / 100)
+		if c.Refund > 0 {
+			c.RefundStatus = RefundPending
+		}
+	}
+	return c
+}
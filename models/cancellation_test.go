@@ -0,0 +1,100 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCancellationFee(t *testing.T) {
+	policy := CancellationPolicy{{HoursBefore: 48, Fee: 30}, {HoursBefore: 7 * 24, Fee: 0}}
+	tests := []struct {
+		hours float64
+		fee   float64
+	}{
+		{hours: 200, fee: 0},
+		{hours: 168, fee: 0},
+		{hours: 100, fee: 30},
+		{hours: 48, fee: 30},
+		{hours: 47.99, fee: 100},
+		{hours: -2, fee: 100},
+	}
+	for _, tt := range tests {
+		if got := policy.Fee(tt.hours); got != tt.fee {
+			t.Errorf("Fee(%v) = %v, want %v", tt.hours, got, tt.fee)
+		}
+	}
+	if got := (CancellationPolicy{}).Fee(-2); got != 0 {
+		t.Errorf("Fee without rules = %v, want 0", got)
+	}
+}
+
+func TestCancellationPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy CancellationPolicy
+		valid  bool
+	}{
+		{name: "no rules", valid: true},
+		{name: "rules", policy: CancellationPolicy{{HoursBefore: 48, Fee: 30}, {HoursBefore: 0, Fee: 100}}, valid: true},
+		{name: "after the move", policy: CancellationPolicy{{HoursBefore: -1, Fee: 30}}},
+		{name: "fee above 100", policy: CancellationPolicy{{HoursBefore: 48, Fee: 130}}},
+		{name: "same start", policy: CancellationPolicy{{HoursBefore: 48, Fee: 30}, {HoursBefore: 48, Fee: 50}}},
+	}
+	for _, tt := range tests {
+		if err := tt.policy.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
+
+func TestProfileCancellation(t *testing.T) {
+	start := time.Date(2030, 4, 10, 8, 0, 0, 0, MovingLocation)
+	policy := CancellationPolicy{{HoursBefore: 7 * 24, Fee: 0}, {HoursBefore: 48, Fee: 30}}
+	tests := []struct {
+		name   string
+		status OfferStatus
+		before time.Duration
+		fee    float64
+		refund float64
+		due    bool
+	}{
+		{name: "paid, a week before", status: OfferPaid, before: 8 * 24 * time.Hour, refund: 400, due: true},
+		{name: "paid, days before", status: OfferPaid, before: 72 * time.Hour, fee: 30, refund: 280, due: true},
+		{name: "paid, the day before", status: OfferPaid, before: 24 * time.Hour, fee: 100},
+		{name: "accepted", status: OfferAccepted, before: 72 * time.Hour, fee: 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Profile{MovingTime: start, Offer: Offer{Status: tt.status, TotalValue: 400}}
+			c := p.Cancellation(policy, "motivo", start.Add(-tt.before))
+			if c.Fee != tt.fee || c.Refund != tt.refund || c.RefundDue() != tt.due {
+				t.Errorf("cancellation %+v, due %v", c, c.RefundDue())
+			}
+			if tt.due && c.RefundStatus != RefundPending {
+				t.Errorf("refund status %q, want %q", c.RefundStatus, RefundPending)
+			}
+		})
+	}
+}
+
+func TestRefundDue(t *testing.T) {
+	tests := []struct {
+		c   Cancellation
+		due bool
+	}{
+		{Cancellation{Refund: 10, RefundStatus: RefundPending}, true},
+		{Cancellation{Refund: 10, RefundStatus: RefundFailed}, true},
+		{Cancellation{Refund: 10, RefundStatus: RefundRequested}, false},
+		{Cancellation{Refund: 10, RefundStatus: RefundRequested, RefundID: "re_1"}, false},
+		{Cancellation{Refund: 10, RefundStatus: RefundDone}, false},
+		{Cancellation{Refund: 0, RefundStatus: RefundPending}, false},
+		// recorded before refund statuses
+		{Cancellation{Refund: 10}, true},
+		{Cancellation{Refund: 10, RefundID: "re_1"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.c.RefundDue(); got != tt.due {
+			t.Errorf("%+v: due = %v, want %v", tt.c, got, tt.due)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"regexp"
+)
+
+var ErrCatalogItemNotFound = errors.New("Catalog item not found")
+
+var slug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CatalogItem describes a kind of item, per unit
+type CatalogItem struct {
+	ID          string        `bson:"_id" json:"id"`
+	Name        string        `bson:"name" json:"nome"`
+	Volume      float64       `bson:"volume" json:"volume"`
+	Weight      float64       `bson:"weight" json:"peso"`
+	Fragile     bool          `bson:"fragile" json:"fragil"`
+	Disassembly bool          `bson:"disassembly" json:"desmontagem"`
+	Packing     []PackingNeed `bson:"packing" json:"embalagem"`
+}
+
+// PackingNeed is the packing material used for one unit of an item
+type PackingNeed struct {
+	Material string `bson:"material" json:"material"`
+	Quantity int    `bson:"quantity" json:"quantidade"`
+}
+
+// CatalogRepository stores the catalog of item types
+type CatalogRepository interface {
+	List() ([]CatalogItem, error)
+	Get(id string) (CatalogItem, error)
+	Save(item CatalogItem) error
+	Delete(id string) error
+}
+
+// Validate checks the id is a slug and the measures are not negative
+func (c *CatalogItem) Validate() error {
+	if !slug.MatchString(c.ID) {
+		return errors.New("Catalog id must be lowercase letters, digits and dashes")
+	}
+	if c.Name == "" {
+		return errors.New("Catalog item name is required")
+	}
+	if c.Volume < 0 || c.Weight < 0 {
+		return errors.New("Catalog item volume and weight cannot be negative")
+	}
+	for _, need := range c.Packing {
+		if need.Material == "" || need.Quantity < 1 {
+			return errors.New("Packing needs a material and a positive quantity")
+		}
+	}
+	return nil
+}
+
+// DefaultCatalog is stored when the catalog is empty.
+// It also covers the item types created before the catalog existed
+func DefaultCatalog() []CatalogItem {
+	return []CatalogItem{
+		{ID: "moveis", Name: "Móveis diversos", Volume: 0.5, Weight: 30, Disassembly: true, Packing: []PackingNeed{{"plastico-bolha", 2}}},
+		{ID: "tv", Name: "Televisão", Volume: 0.15, Weight: 12, Fragile: true, Packing: []PackingNeed{{"plastico-bolha", 2}, {"caixa-grande", 1}}},
+		{ID: "sofa", Name: "Sofá 3 lugares", Volume: 1.8, Weight: 60, Packing: []PackingNeed{{"filme-stretch", 1}}},
+		{ID: "cama-casal", Name: "Cama de casal", Volume: 1.5, Weight: 50, Disassembly: true, Packing: []PackingNeed{{"filme-stretch", 1}}},
+		{ID: "colchao", Name: "Colchão", Volume: 0.6, Weight: 25, Packing: []PackingNeed{{"capa-colchao", 1}}},
+		{ID: "geladeira", Name: "Geladeira", Volume: 1.1, Weight: 70, Packing: []PackingNeed{{"filme-stretch", 1}}},
+		{ID: "fogao", Name: "Fogão", Volume: 0.4, Weight: 35},
+		{ID: "maquina-lavar", Name: "Máquina de lavar", Volume: 0.5, Weight: 65},
+		{ID: "guarda-roupa", Name: "Guarda-roupa", Volume: 2.0, Weight: 90, Disassembly: true, Packing: []PackingNeed{{"filme-stretch", 2}}},
+		{ID: "mesa", Name: "Mesa de jantar", Volume: 0.8, Weight: 30, Disassembly: true},
+		{ID: "cadeira", Name: "Cadeira", Volume: 0.2, Weight: 5},
+		{ID: "caixa", Name: "Caixa de papelão", Volume: 0.06, Weight: 15, Packing: []PackingNeed{{"caixa-media", 1}}},
+		{ID: "loucas", Name: "Louças", Volume: 0.01, Weight: 1, Fragile: true, Packing: []PackingNeed{{"papel-seda", 2}}},
+	}
+}
+
+// LoadCatalog reads a JSON array of catalog items from a file
+func LoadCatalog(path string) (items []CatalogItem, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &items)
+	return
+}
+
+// SeedCatalog saves the given items when the catalog is empty
+func SeedCatalog(r CatalogRepository, items []CatalogItem) error {
+	existing, err := r.List()
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	for _, item := range items {
+		if err = item.Validate(); err != nil {
+			return err
+		}
+		if err = r.Save(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Catalog indexes catalog items by id
+type Catalog map[string]CatalogItem
+
+func NewCatalog(items []CatalogItem) Catalog {
+	c := make(Catalog, len(items))
+	for _, item := range items {
+		c[item.ID] = item
+	}
+	return c
+}
+
+// Lookup finds the catalog entry of an inventory item, using its
+// type when it has no catalog reference
+func (c Catalog) Lookup(item Item) (CatalogItem, bool) {
+	id := item.CatalogID
+	if id == "" {
+		id = item.Type
+	}
+	entry, ok := c[id]
+	return entry, ok
+}
+
+// InventorySummary totals what the catalog knows about an inventory
+type InventorySummary struct {
+	Load        Load           `json:"carga"`
+	Fragile     int            `json:"itens_frageis"`
+	Disassembly int            `json:"itens_desmontagem"`
+	Packing     map[string]int `json:"embalagem"`
+	Unknown     []string       `json:"itens_sem_catalogo"`
+}
+
+// Summary adds up the measures of every item in the inventory.
+// A box with no catalogued item counts as a StandardBox
+func (p *Profile) Summary(c Catalog) InventorySummary {
+	s := InventorySummary{Packing: make(map[string]int), Unknown: []string{}}
+	unknown := make(map[string]bool)
+	for _, room := range p.Inventory {
+		for _, box := range room.Boxes {
+			measured := false
+			for _, item := range box.Items {
+				entry, ok := c.Lookup(item)
+				if !ok {
+					if !unknown[item.Type] {
+						unknown[item.Type] = true
+						s.Unknown = append(s.Unknown, item.Type)
+					}
+					continue
+				}
+				measured = true
+				quantity := float64(item.Quantity)
+				s.Load.Volume += entry.Volume * quantity
+				s.Load.Weight += entry.Weight * quantity
+				if entry.Fragile {
+					s.Fragile += item.Quantity
+				}
+				if entry.Disassembly {
+					s.Disassembly += item.Quantity
+				}
+				for _, need := range entry.Packing {
+					s.Packing[need.Material] += need.Quantity * item.Quantity
+				}
+			}
+			if !measured {
+				s.Load.Volume += StandardBox.Volume
+				s.Load.Weight += StandardBox.Weight
+			}
+		}
+	}
+	return s
+}
+
+// Fragile tells if any item of the box is fragile
+func (b *Box) Fragile(c Catalog) bool {
+	for _, item := range b.Items {
+		if entry, ok := c.Lookup(item); ok && entry.Fragile {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryCatalogRepository keeps item types in a map
+type MemoryCatalogRepository struct {
+	mu    sync.RWMutex
+	items map[string]CatalogItem
+}
+
+func NewMemoryCatalogRepository() *MemoryCatalogRepository {
+	return &MemoryCatalogRepository{items: make(map[string]CatalogItem)}
+}
+
+func (r *MemoryCatalogRepository) List() ([]CatalogItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make([]CatalogItem, 0, len(r.items))
+	for _, item := range r.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+func (r *MemoryCatalogRepository) Get(id string) (CatalogItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	item, ok := r.items[id]
+	if !ok {
+		return item, ErrCatalogItemNotFound
+	}
+	return item, nil
+}
+
+func (r *MemoryCatalogRepository) Save(item CatalogItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item.Packing = append([]PackingNeed{}, item.Packing...)
+	r.items[item.ID] = item
+	return nil
+}
+
+func (r *MemoryCatalogRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[id]; !ok {
+		return ErrCatalogItemNotFound
+	}
+	delete(r.items, id)
+	return nil
+}
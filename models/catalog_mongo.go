@@ -0,0 +1,43 @@
+package models
+
+import mgo "gopkg.in/mgo.v2"
+
+// MongoCatalogRepository stores item types in the catalog collection
+type MongoCatalogRepository struct {
+	DB *mgo.Database
+}
+
+func NewMongoCatalogRepository(db *mgo.Database) *MongoCatalogRepository {
+	return &MongoCatalogRepository{DB: db}
+}
+
+func (r *MongoCatalogRepository) catalog() *mgo.Collection {
+	return r.DB.C("catalog")
+}
+
+func (r *MongoCatalogRepository) List() (items []CatalogItem, err error) {
+	items = []CatalogItem{}
+	err = r.catalog().Find(nil).Sort("_id").All(&items)
+	return
+}
+
+func (r *MongoCatalogRepository) Get(id string) (item CatalogItem, err error) {
+	err = r.catalog().FindId(id).One(&item)
+	if err == mgo.ErrNotFound {
+		err = ErrCatalogItemNotFound
+	}
+	return
+}
+
+func (r *MongoCatalogRepository) Save(item CatalogItem) (err error) {
+	_, err = r.catalog().UpsertId(item.ID, &item)
+	return
+}
+
+func (r *MongoCatalogRepository) Delete(id string) (err error) {
+	err = r.catalog().RemoveId(id)
+	if err == mgo.ErrNotFound {
+		err = ErrCatalogItemNotFound
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	// the sofa is looked up by type, the quadro is not in
+	// the catalog and the boxes without known items count
+	// as standard boxes
+	p := Profile{Inventory: []Room{
+		{Name: "sala", Boxes: []Box{
+			{Items: []Item{{Type: "Televisão", CatalogID: "tv", Quantity: 2}, {Type: "sofa", Quantity: 1}}},
+			{Items: []Item{{Type: "quadro", Quantity: 3}}},
+			{Items: []Item{}},
+		}},
+	}}
+	s := p.Summary(NewCatalog(DefaultCatalog()))
+	if math.Abs(s.Load.Volume-2.3) > 1e-9 || s.Load.Weight != 124 {
+		t.Errorf("load %+v", s.Load)
+	}
+	if s.Fragile != 2 || s.Packing["plastico-bolha"] != 4 || s.Packing["filme-stretch"] != 1 {
+		t.Errorf("summary %+v", s)
+	}
+	if len(s.Unknown) != 1 || s.Unknown[0] != "quadro" {
+		t.Errorf("unknown items %v", s.Unknown)
+	}
+}
+
+func TestCatalogItemValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		item  CatalogItem
+		valid bool
+	}{
+		{name: "item", item: CatalogItem{ID: "tv-55", Name: "TV 55", Volume: 0.2, Weight: 18}, valid: true},
+		{name: "id not a slug", item: CatalogItem{ID: "TV 55", Name: "TV 55"}},
+		{name: "no name", item: CatalogItem{ID: "tv-55"}},
+		{name: "negative volume", item: CatalogItem{ID: "tv-55", Name: "TV 55", Volume: -1}},
+		{name: "packing without material", item: CatalogItem{ID: "tv-55", Name: "TV 55", Packing: []PackingNeed{{Quantity: 1}}}},
+	}
+	for _, tt := range tests {
+		if err := tt.item.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+	for _, item := range DefaultCatalog() {
+		if err := item.Validate(); err != nil {
+			t.Errorf("default item %s: %v", item.ID, err)
+		}
+	}
+}
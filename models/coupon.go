@@ -0,0 +1,149 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCouponNotFound  = errors.New("Coupon not found")
+	ErrCouponExpired   = errors.New("Coupon is not valid at this time")
+	ErrCouponVehicle   = errors.New("Coupon does not apply to this vehicle type")
+	ErrCouponMinOrder  = errors.New("Offer does not reach the coupon minimum value")
+	ErrCouponExhausted = errors.New("Coupon usage limit reached")
+	ErrCouponNoUser    = errors.New("Coupons are only for profiles with a phone number")
+)
+
+var couponCode = regexp.MustCompile(`^[A-Z0-9-]{3,32}$`)
+
+// CouponKind tells how a coupon discounts the price
+type CouponKind string
+
+const (
+	CouponPercentage CouponKind = "percentual"
+	CouponFixed      CouponKind = "fixo"
+)
+
+// Coupon is a promo code of a discount campaign.
+// Zero limits and dates leave the coupon unrestricted
+type Coupon struct {
+	Code           string     `bson:"_id" json:"codigo"`
+	Kind           CouponKind `bson:"kind" json:"tipo"`
+	Value          float64    `bson:"value" json:"valor"`
+	MinOrder       float64    `bson:"min_order" json:"pedido_minimo"`
+	Vehicles       []int      `bson:"vehicles" json:"veiculos"`
+	MaxUses        int        `bson:"max_uses" json:"limite_total"`
+	MaxUsesPerUser int        `bson:"max_uses_per_user" json:"limite_por_usuario"`
+	ValidFrom      time.Time  `bson:"valid_from" json:"valido_de"`
+	ValidUntil     time.Time  `bson:"valid_until" json:"valido_ate"`
+	// Uses counts every redemption, PerUser the ones of each
+	// user, keyed by the digits of their phone number
+	Uses    int            `bson:"uses" json:"usos"`
+	PerUser map[string]int `bson:"per_user,omitempty" json:"-"`
+}
+
+// CouponRepository stores coupons and counts their uses.
+// Save keeps the usage counters of an existing coupon, and
+// Redeem must not go past the limits when called concurrently
+type CouponRepository interface {
+	List() ([]Coupon, error)
+	Get(code string) (Coupon, error)
+	Save(c Coupon) error
+	Delete(code string) error
+	// Redeem counts a use of the coupon by the user,
+	// failing with ErrCouponExhausted past its limits and
+	// with ErrCouponNoUser when the user is empty
+	Redeem(c Coupon, user string) error
+	Release(code, user string) error
+}
+
+// NormalizeCouponCode writes codes in upper case, so
+// customers can type them in any case
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CouponUser is the key of a user in the usage counters
+func CouponUser(phone string) string {
+	return strings.TrimPrefix(phone, "+")
+}
+
+// Validate checks the code and the campaign rules
+func (c *Coupon) Validate() error {
+	if !couponCode.MatchString(c.Code) {
+		return errors.New("Coupon code must have 3 to 32 letters, digits and dashes")
+	}
+	switch c.Kind {
+	case CouponPercentage:
+		if c.Value <= 0 || c.Value > 100 {
+			return errors.New("Percentage coupons discount from 0 to 100")
+		}
+	case CouponFixed:
+		if c.Value <= 0 {
+			return errors.New("Fixed coupons discount a positive value")
+		}
+	default:
+		return fmt.Errorf("Coupon kind must be %s or %s", CouponPercentage, CouponFixed)
+	}
+	if c.MinOrder < 0 || c.MaxUses < 0 || c.MaxUsesPerUser < 0 {
+		return errors.New("Coupon limits cannot be negative")
+	}
+	if !c.ValidFrom.IsZero() && !c.ValidUntil.IsZero() && !c.ValidUntil.After(c.ValidFrom) {
+		return errors.New("Coupon must be valid until after it starts")
+	}
+	return nil
+}
+
+// Check tells whether the coupon applies to the offer
+func (c *Coupon) Check(o *Offer, at time.Time) error {
+	if at.Before(c.ValidFrom) || !c.ValidUntil.IsZero() && at.After(c.ValidUntil) {
+		return ErrCouponExpired
+	}
+	if len(c.Vehicles) > 0 {
+		allowed := false
+		for _, v := range c.Vehicles {
+			allowed = allowed || v == o.VehicleType
+		}
+		if !allowed {
+			return ErrCouponVehicle
+		}
+	}
+	if o.undiscounted() < c.MinOrder {
+		return ErrCouponMinOrder
+	}
+	return nil
+}
+
+// ApplyCoupon replaces the discount of the offer with the
+// coupon one, taken from the price before taxes
+func (o *Offer) ApplyCoupon(c Coupon) {
+	o.RemoveCoupon()
+	base := o.undiscounted()
+	discount := base * c.Value / 100
+	description := fmt.Sprintf("Cupom %s (%g%%)", c.Code, c.Value)
+	if c.Kind == CouponFixed {
+		discount = c.Value
+		description = "Cupom " + c.Code
+	}
+	if discount > base {
+		discount = base
+	}
+	o.Coupon = c.Code
+	o.AddLine(newLineItem(LineDiscount, description, 1, -roundCents(discount)))
+}
+
+// RemoveCoupon takes the discount out of the offer
+func (o *Offer) RemoveCoupon() {
+	lines := o.Breakdown[:0:0]
+	for _, item := range o.Breakdown {
+		if item.Kind != LineDiscount {
+			lines = append(lines, item)
+		}
+	}
+	o.Breakdown = lines
+	o.Coupon = ""
+	o.reprice()
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryCouponRepository keeps coupons and their uses in a map
+type MemoryCouponRepository struct {
+	mu      sync.Mutex
+	coupons map[string]Coupon
+}
+
+func NewMemoryCouponRepository() *MemoryCouponRepository {
+	return &MemoryCouponRepository{coupons: make(map[string]Coupon)}
+}
+
+func (r *MemoryCouponRepository) List() ([]Coupon, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	coupons := make([]Coupon, 0, len(r.coupons))
+	for _, c := range r.coupons {
+		coupons = append(coupons, c.clone())
+	}
+	sort.Slice(coupons, func(i, j int) bool { return coupons[i].Code < coupons[j].Code })
+	return coupons, nil
+}
+
+func (r *MemoryCouponRepository) Get(code string) (Coupon, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.coupons[code]
+	if !ok {
+		return c, ErrCouponNotFound
+	}
+	return c.clone(), nil
+}
+
+func (r *MemoryCouponRepository) Save(c Coupon) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing := r.coupons[c.Code]
+	c.Uses, c.PerUser = existing.Uses, existing.PerUser
+	r.coupons[c.Code] = c.clone()
+	return nil
+}
+
+func (r *MemoryCouponRepository) Delete(code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.coupons[code]; !ok {
+		return ErrCouponNotFound
+	}
+	delete(r.coupons, code)
+	return nil
+}
+
+func (r *MemoryCouponRepository) Redeem(c Coupon, user string) error {
+	if user == "" {
+		return ErrCouponNoUser
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.coupons[c.Code]
+	if !ok {
+		return ErrCouponNotFound
+	}
+	if c.MaxUses > 0 && stored.Uses >= c.MaxUses ||
+		c.MaxUsesPerUser > 0 && stored.PerUser[user] >= c.MaxUsesPerUser {
+		return ErrCouponExhausted
+	}
+	if stored.PerUser == nil {
+		stored.PerUser = make(map[string]int)
+	}
+	stored.Uses++
+	stored.PerUser[user]++
+	r.coupons[c.Code] = stored
+	return nil
+}
+
+func (r *MemoryCouponRepository) Release(code, user string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.coupons[code]
+	if !ok {
+		return ErrCouponNotFound
+	}
+	if stored.PerUser[user] > 0 {
+		stored.Uses--
+		stored.PerUser[user]--
+	}
+	r.coupons[code] = stored
+	return nil
+}
+
+func (c Coupon) clone() Coupon {
+	c.Vehicles = append([]int(nil), c.Vehicles...)
+	perUser := make(map[string]int, len(c.PerUser))
+	for user, uses := range c.PerUser {
+		perUser[user] = uses
+	}
+	c.PerUser = perUser
+	return c
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MongoCouponRepository stores coupons in the coupons collection,
+// counting their uses in the same document
+type MongoCouponRepository struct {
+	DB *mgo.Database
+}
+
+func NewMongoCouponRepository(db *mgo.Database) *MongoCouponRepository {
+	return &MongoCouponRepository{DB: db}
+}
+
+func (r *MongoCouponRepository) coupons() *mgo.Collection {
+	return r.DB.C("coupons")
+}
+
+func (r *MongoCouponRepository) List() (coupons []Coupon, err error) {
+	coupons = []Coupon{}
+	err = r.coupons().Find(nil).Sort("_id").All(&coupons)
+	return
+}
+
+func (r *MongoCouponRepository) Get(code string) (c Coupon, err error) {
+	err = r.coupons().FindId(code).One(&c)
+	if err == mgo.ErrNotFound {
+		err = ErrCouponNotFound
+	}
+	return
+}
+
+// Save only sets the campaign rules, so uses
+// counted meanwhile are not overwritten
+func (r *MongoCouponRepository) Save(c Coupon) (err error) {
+	_, err = r.coupons().UpsertId(c.Code, bson.M{"$set": bson.M{
+		"kind":              c.Kind,
+		"value":             c.Value,
+		"min_order":         c.MinOrder,
+		"vehicles":          c.Vehicles,
+		"max_uses":          c.MaxUses,
+		"max_uses_per_user": c.MaxUsesPerUser,
+		"valid_from":        c.ValidFrom,
+		"valid_until":       c.ValidUntil,
+	}})
+	return
+}
+
+func (r *MongoCouponRepository) Delete(code string) (err error) {
+	err = r.coupons().RemoveId(code)
+	if err == mgo.ErrNotFound {
+		err = ErrCouponNotFound
+	}
+	return
+}
+
+// Redeem increments the counters only while they are under
+// the limits, so concurrent redemptions cannot go past them.
+//
+// Redeem keys the uses of the user by its phone number,
+// which must be there for the key to be a valid path
+func (r *MongoCouponRepository) Redeem(c Coupon, user string) (err error) {
+	if user == "" {
+		return ErrCouponNoUser
+	}
+	perUser := "per_user." + user
+	query := bson.M{"_id": c.Code}
+	if c.MaxUses > 0 {
+		query["uses"] = bson.M{"$lt": c.MaxUses}
+	}
+	if c.MaxUsesPerUser > 0 {
+		query[perUser] = bson.M{"$not": bson.M{"$gte": c.MaxUsesPerUser}}
+	}
+	err = r.coupons().Update(query, bson.M{"$inc": bson.M{"uses": 1, perUser: 1}})
+	if err == mgo.ErrNotFound {
+		if _, err = r.Get(c.Code); err == nil {
+			err = ErrCouponExhausted
+		}
+	}
+	return
+}
+
+func (r *MongoCouponRepository) Release(code, user string) (err error) {
+	if user == "" {
+		return ErrCouponNoUser
+	}
+	perUser := "per_user." + user
+	err = r.coupons().Update(
+		bson.M{"_id": code, perUser: bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"uses": -1, perUser: -1}},
+	)
+	if err == mgo.ErrNotFound {
+		err = ErrCouponNotFound
+	}
+	return
+}
@@ -0,0 +1,241 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pricedOffer is vehicle 2 driving 10 km on a weekday,
+// 376 before taxes, with the given tax rate
+func pricedOffer(t *testing.T, taxRate float64) Offer {
+	t.Helper()
+	tariff := testTariff()
+	tariff.TaxRate = taxRate
+	o := Offer{VehicleType: 2, Distance: 10}
+	if err := o.CalculateTotalValue(tariff, Move{Date: day("2030-04-10")}); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestCouponCheck(t *testing.T) {
+	now := time.Date(2030, 4, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		coupon Coupon
+		err    error
+	}{
+		{name: "unrestricted", coupon: Coupon{}},
+		{name: "within dates", coupon: Coupon{ValidFrom: now.Add(-time.Hour), ValidUntil: now.Add(time.Hour)}},
+		{name: "not started", coupon: Coupon{ValidFrom: now.Add(time.Hour)}, err: ErrCouponExpired},
+		{name: "ended", coupon: Coupon{ValidUntil: now.Add(-time.Hour)}, err: ErrCouponExpired},
+		{name: "vehicle", coupon: Coupon{Vehicles: []int{1, 2}}},
+		{name: "other vehicle", coupon: Coupon{Vehicles: []int{1, 3}}, err: ErrCouponVehicle},
+		{name: "minimum reached", coupon: Coupon{MinOrder: 376}},
+		{name: "minimum not reached", coupon: Coupon{MinOrder: 376.01}, err: ErrCouponMinOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := pricedOffer(t, 0)
+			if err := tt.coupon.Check(&o, now); err != tt.err {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestApplyCoupon(t *testing.T) {
+	tests := []struct {
+		name    string
+		coupon  Coupon
+		taxRate float64
+		lines   []float64
+		total   float64
+	}{
+		{
+			name:   "percentage",
+			coupon: Coupon{Code: "DEZ", Kind: CouponPercentage, Value: 10},
+			lines:  []float64{350, 26, -37.6},
+			total:  338.4,
+		},
+		{
+			name:   "fixed",
+			coupon: Coupon{Code: "CEM", Kind: CouponFixed, Value: 100},
+			lines:  []float64{350, 26, -100},
+			total:  276,
+		},
+		{
+			name:   "fixed above the price",
+			coupon: Coupon{Code: "MIL", Kind: CouponFixed, Value: 1000},
+			lines:  []float64{350, 26, -376},
+			total:  0,
+		},
+		{
+			name:    "taxes after the discount",
+			coupon:  Coupon{Code: "DEZ", Kind: CouponPercentage, Value: 10},
+			taxRate: 10,
+			lines:   []float64{350, 26, -37.6, 33.84},
+			total:   372.24,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := pricedOffer(t, tt.taxRate)
+			o.ApplyCoupon(tt.coupon)
+			checkBreakdown(t, o, tt.lines, tt.total)
+			if o.Coupon != tt.coupon.Code {
+				t.Errorf("coupon = %q, want %q", o.Coupon, tt.coupon.Code)
+			}
+		})
+	}
+}
+
+func TestApplyCouponReplacesAndRemoves(t *testing.T) {
+	o := pricedOffer(t, 10)
+	o.ApplyCoupon(Coupon{Code: "DEZ", Kind: CouponPercentage, Value: 10})
+	o.ApplyCoupon(Coupon{Code: "CEM", Kind: CouponFixed, Value: 100})
+	checkBreakdown(t, o, []float64{350, 26, -100, 27.6}, 303.6)
+	o.RemoveCoupon()
+	checkBreakdown(t, o, []float64{350, 26, 37.6}, 413.6)
+	if o.Coupon != "" {
+		t.Errorf("coupon = %q after removing it", o.Coupon)
+	}
+}
+
+func TestRedeemLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		coupon Coupon
+		// users redeem the coupon in order, err is the
+		// outcome of each redemption
+		users []string
+		errs  []error
+		uses  int
+	}{
+		{
+			name:   "unlimited",
+			coupon: Coupon{Code: "LIVRE"},
+			users:  []string{"a", "a", "b"},
+			errs:   []error{nil, nil, nil},
+			uses:   3,
+		},
+		{
+			name:   "total limit",
+			coupon: Coupon{Code: "DOIS", MaxUses: 2},
+			users:  []string{"a", "b", "c"},
+			errs:   []error{nil, nil, ErrCouponExhausted},
+			uses:   2,
+		},
+		{
+			name:   "limit per user",
+			coupon: Coupon{Code: "UMPOR", MaxUsesPerUser: 1},
+			users:  []string{"a", "a", "b"},
+			errs:   []error{nil, ErrCouponExhausted, nil},
+			uses:   2,
+		},
+		{
+			name:   "both limits",
+			coupon: Coupon{Code: "AMBOS", MaxUses: 2, MaxUsesPerUser: 1},
+			users:  []string{"a", "a", "b", "c"},
+			errs:   []error{nil, ErrCouponExhausted, nil, ErrCouponExhausted},
+			uses:   2,
+		},
+		{
+			name:   "no user",
+			coupon: Coupon{Code: "SEMFONE"},
+			users:  []string{""},
+			errs:   []error{ErrCouponNoUser},
+			uses:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewMemoryCouponRepository()
+			if err := r.Save(tt.coupon); err != nil {
+				t.Fatal(err)
+			}
+			for i, user := range tt.users {
+				if err := r.Redeem(tt.coupon, user); err != tt.errs[i] {
+					t.Errorf("redemption %d by %q: err = %v, want %v", i+1, user, err, tt.errs[i])
+				}
+			}
+			c, err := r.Get(tt.coupon.Code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Uses != tt.uses {
+				t.Errorf("uses = %d, want %d", c.Uses, tt.uses)
+			}
+		})
+	}
+}
+
+func TestReleaseGivesTheUseBack(t *testing.T) {
+	r := NewMemoryCouponRepository()
+	c := Coupon{Code: "UMPOR", MaxUses: 1, MaxUsesPerUser: 1}
+	if err := r.Save(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Redeem(c, "a"); err != nil {
+		t.Fatal(err)
+	}
+	// releasing a use the user never had changes nothing
+	if err := r.Release(c.Code, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Redeem(c, "b"); err != ErrCouponExhausted {
+		t.Fatalf("err = %v, want %v", err, ErrCouponExhausted)
+	}
+	if err := r.Release(c.Code, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Redeem(c, "b"); err != nil {
+		t.Errorf("err = %v after the use was given back", err)
+	}
+	if err := r.Save(Coupon{Code: "UMPOR", MaxUses: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := r.Get(c.Code); saved.Uses != 1 {
+		t.Errorf("uses = %d after saving the coupon again, want 1", saved.Uses)
+	}
+}
+
+func TestRedeemConcurrently(t *testing.T) {
+	r := NewMemoryCouponRepository()
+	c := Coupon{Code: "TRES", MaxUses: 3, MaxUsesPerUser: 2}
+	if err := r.Save(c); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, 40)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = r.Redeem(c, fmt.Sprintf("user %d", i%4))
+		}(i)
+	}
+	wg.Wait()
+	redeemed := 0
+	for _, err := range errs {
+		if err == nil {
+			redeemed++
+		} else if err != ErrCouponExhausted {
+			t.Errorf("err = %v", err)
+		}
+	}
+	saved, err := r.Get(c.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redeemed != c.MaxUses || saved.Uses != c.MaxUses {
+		t.Errorf("%d redemptions and %d uses, want %d", redeemed, saved.Uses, c.MaxUses)
+	}
+	for user, uses := range saved.PerUser {
+		if uses > c.MaxUsesPerUser {
+			t.Errorf("%s used it %d times", user, uses)
+		}
+	}
+}
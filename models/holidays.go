@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// fixedHolidays are the Brazilian national holidays on the same day every year
+var fixedHolidays = map[time.Month]map[int]string{
+	time.January:   {1: "Confraternização Universal"},
+	time.April:     {21: "Tiradentes"},
+	time.May:       {1: "Dia do Trabalho"},
+	time.September: {7: "Independência"},
+	time.October:   {12: "Nossa Senhora Aparecida"},
+	time.November:  {2: "Finados", 15: "Proclamação da República"},
+	time.December:  {25: "Natal"},
+}
+
+// Holiday tells whether the day is a national holiday in Brazil.
+// Carnival and Corpus Christi are optional days off for the
+// federal government, but the whole country stops on them
+func Holiday(day time.Time) (name string, ok bool) {
+	y, m, d := day.Date()
+	if name, ok = fixedHolidays[m][d]; ok {
+		return
+	}
+	// national since Lei 14.759/2023
+	if y >= 2024 && m == time.November && d == 20 {
+		return "Consciência Negra", true
+	}
+	easter := Easter(y)
+	date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	switch int(date.Sub(easter).Hours() / 24) {
+	case -48, -47:
+		return "Carnaval", true
+	case -2:
+		return "Sexta-feira Santa", true
+	case 60:
+		return "Corpus Christi", true
+	}
+	return "", false
+}
+
+// Easter returns the Easter Sunday of the year, from the
+// anonymous Gregorian algorithm
+func Easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
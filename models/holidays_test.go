@@ -0,0 +1,98 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEaster(t *testing.T) {
+	tests := map[int]string{
+		2019: "2019-04-21",
+		2024: "2024-03-31",
+		2025: "2025-04-20",
+		2038: "2038-04-25",
+	}
+	for year, want := range tests {
+		if got := Easter(year).Format(DateLayout); got != want {
+			t.Errorf("Easter(%d) = %s, want %s", year, got, want)
+		}
+	}
+}
+
+func TestHoliday(t *testing.T) {
+	tests := []struct {
+		date string
+		name string
+	}{
+		{date: "2019-01-01", name: "Confraternização Universal"},
+		{date: "2019-04-21", name: "Tiradentes"},
+		{date: "2019-12-25", name: "Natal"},
+		{date: "2019-03-04", name: "Carnaval"},
+		{date: "2019-03-05", name: "Carnaval"},
+		{date: "2019-03-06"},
+		{date: "2019-04-19", name: "Sexta-feira Santa"},
+		{date: "2019-06-20", name: "Corpus Christi"},
+		{date: "2025-03-03", name: "Carnaval"},
+		{date: "2025-06-19", name: "Corpus Christi"},
+		{date: "2019-06-21"},
+		// national since 2024
+		{date: "2023-11-20"},
+		{date: "2024-11-20", name: "Consciência Negra"},
+		{date: "2025-11-20", name: "Consciência Negra"},
+	}
+	for _, tt := range tests {
+		day, err := time.ParseInLocation(DateLayout, tt.date, MovingLocation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, ok := Holiday(day)
+		if name != tt.name || ok != (tt.name != "") {
+			t.Errorf("Holiday(%s) = %q, %v, want %q", tt.date, name, ok, tt.name)
+		}
+	}
+}
+
+func TestDemandRatesValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rates DemandRates
+		valid bool
+	}{
+		{name: "no rules", valid: true},
+		{name: "rules", rates: DemandRates{Weekend: 1.15, MonthEnd: 1.1, MonthEndDays: 3, Holiday: 1.25,
+			Utilisation: []UtilisationRate{{From: 0.8, Multiplier: 1.25}}}, valid: true},
+		{name: "negative multiplier", rates: DemandRates{Weekend: -1}},
+		{name: "multiplier too high", rates: DemandRates{Holiday: 11}},
+		{name: "threshold above 1", rates: DemandRates{Utilisation: []UtilisationRate{{From: 1.5, Multiplier: 1.1}}}},
+		{name: "too many month end days", rates: DemandRates{MonthEnd: 1.1, MonthEndDays: 29}},
+	}
+	for _, tt := range tests {
+		if err := tt.rates.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
+
+func TestDemandRatesAddUp(t *testing.T) {
+	rates := DemandRates{Weekend: 1.15, MonthEnd: 1.1, MonthEndDays: 3, Holiday: 1.25}
+	// a Saturday, on the last day of the month
+	m := Move{Date: time.Date(2019, 8, 31, 0, 0, 0, 0, MovingLocation)}
+	items := rates.For(m, 1, 1000)
+	if len(items) != 2 {
+		t.Fatalf("lines %+v", items)
+	}
+	var total float64
+	for _, item := range items {
+		total += item.Subtotal
+	}
+	if total != 250 {
+		t.Errorf("increase %v, want 250", total)
+	}
+	// Consciência Negra only raises the price since it is national
+	if items := rates.For(Move{Date: time.Date(2023, 11, 20, 0, 0, 0, 0, MovingLocation)}, 1, 1000); len(items) != 0 {
+		t.Errorf("lines on 2023-11-20 %+v", items)
+	}
+	if items := rates.For(Move{Date: time.Date(2024, 11, 20, 0, 0, 0, 0, MovingLocation)}, 1, 1000); len(items) != 1 {
+		t.Errorf("lines on 2024-11-20 %+v", items)
+	}
+}
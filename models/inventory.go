@@ -0,0 +1,286 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	ErrRoomNotFound    = errors.New("Room not found")
+	ErrRoomExists      = errors.New("Room already exists")
+	ErrBoxNotFound     = errors.New("Box not found")
+	ErrItemNotFound    = errors.New("Item not found")
+	ErrConflict        = errors.New("Inventory was changed concurrently, try again")
+	ErrInventoryLocked = errors.New("Cancel the offer to change its inventory")
+)
+
+// RoomIndex returns the position of the room called name
+// inside the inventory
+func (p *Profile) RoomIndex(name string) (int, error) {
+	for i, room := range p.Inventory {
+		if room.Name == name {
+			return i, nil
+		}
+	}
+	return -1, ErrRoomNotFound
+}
+
+// BoxIndex returns the position of a box inside the room, looking
+// it up by ID and falling back to the box number, so the routes
+// also take the number written on the box
+func (r *Room) BoxIndex(ref string) (int, error) {
+	for i, box := range r.Boxes {
+		if box.ID == ref {
+			return i, nil
+		}
+	}
+	if number, err := strconv.Atoi(ref); err == nil {
+		for i, box := range r.Boxes {
+			if box.Number == number {
+				return i, nil
+			}
+		}
+	}
+	return -1, ErrBoxNotFound
+}
+
+// BoxByID returns the position of the room and
+// of the box with the given ID inside it
+func (p *Profile) BoxByID(id string) (i, j int, err error) {
+	for i, room := range p.Inventory {
+		for j, box := range room.Boxes {
+			if box.ID == id {
+				return i, j, nil
+			}
+		}
+	}
+	return -1, -1, ErrBoxNotFound
+}
+
+// ItemIndex returns the position of the item of type t inside the box
+func (b *Box) ItemIndex(t string) (int, error) {
+	for i, item := range b.Items {
+		if item.Type == t {
+			return i, nil
+		}
+	}
+	return -1, ErrItemNotFound
+}
+
+// NewBox builds a box with a fresh identifier, numbered
+// after the boxes already present in the room
+func NewBox(room Room, items []Item) Box {
+	number := 1
+	for _, b := range room.Boxes {
+		if b.Number >= number {
+			number = b.Number + 1
+		}
+	}
+	if items == nil {
+		items = []Item{}
+	}
+	return Box{
+		ID:     bson.NewObjectId().Hex(),
+		Number: number,
+		Label:  fmt.Sprintf("%s #%d", room.Name, number),
+		Items:  items,
+	}
+}
+
+// AssignBoxIDs gives an ID to the boxes stored before boxes had
+// identifiers, and a number and label to the ones without. Numbers
+// follow the position of the box in its room, as they did before
+func (p *Profile) AssignBoxIDs() (assigned int) {
+	for i := range p.Inventory {
+		room := &p.Inventory[i]
+		for j := range room.Boxes {
+			box := &room.Boxes[j]
+			if box.ID != "" {
+				continue
+			}
+			box.ID = bson.NewObjectId().Hex()
+			if box.Number == 0 {
+				box.Number = j + 1
+			}
+			if box.Label == "" {
+				box.Label = fmt.Sprintf("%s #%d", room.Name, box.Number)
+			}
+			assigned++
+		}
+	}
+	return
+}
+
+// NewInventory builds the inventory of a new profile from a
+// template, giving every box a fresh ID and number so
+// nothing sent by the client clashes with existing boxes
+func NewInventory(template []Room) (rooms []Room, err error) {
+	rooms = []Room{}
+	for _, t := range template {
+		room := Room{Name: strings.TrimSpace(t.Name), Boxes: []Box{}}
+		if room.Name == "" {
+			return nil, errors.New("Room name is required")
+		}
+		if _, err = (&Profile{Inventory: rooms}).RoomIndex(room.Name); err == nil {
+			return nil, ErrRoomExists
+		}
+		for _, b := range t.Boxes {
+			box := NewBox(room, b.Items)
+			if label := strings.TrimSpace(b.Label); label != "" {
+				box.Label = label
+			}
+			room.Boxes = append(room.Boxes, box)
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+// AddRoom appends an empty room to the inventory
+func (p *Profile) AddRoom(room Room) error {
+	if _, err := p.RoomIndex(room.Name); err == nil {
+		return ErrRoomExists
+	}
+	if room.Boxes == nil {
+		room.Boxes = []Box{}
+	}
+	p.Inventory = append(p.Inventory, room)
+	return nil
+}
+
+// RenameRoom changes the name of a room keeping its boxes
+func (p *Profile) RenameRoom(oldName, newName string) error {
+	i, err := p.RoomIndex(oldName)
+	if err != nil {
+		return err
+	}
+	if _, err = p.RoomIndex(newName); err == nil {
+		return ErrRoomExists
+	}
+	p.Inventory[i].Name = newName
+	return nil
+}
+
+// RemoveRoom deletes a room and every box inside it
+func (p *Profile) RemoveRoom(name string) error {
+	i, err := p.RoomIndex(name)
+	if err != nil {
+		return err
+	}
+	p.Inventory = append(p.Inventory[:i], p.Inventory[i+1:]...)
+	return nil
+}
+
+// AddBox appends a new box to the room and returns it
+func (p *Profile) AddBox(roomName, label string, items []Item) (box Box, err error) {
+	i, err := p.RoomIndex(roomName)
+	if err != nil {
+		return
+	}
+	box = NewBox(p.Inventory[i], items)
+	if label != "" {
+		box.Label = label
+	}
+	p.Inventory[i].Boxes = append(p.Inventory[i].Boxes, box)
+	return
+}
+
+// RemoveBox deletes the box from the room
+func (p *Profile) RemoveBox(roomName, boxID string) error {
+	i, j, err := p.locateBox(roomName, boxID)
+	if err != nil {
+		return err
+	}
+	boxes := p.Inventory[i].Boxes
+	p.Inventory[i].Boxes = append(boxes[:j], boxes[j+1:]...)
+	return nil
+}
+
+// AddItem puts quantity units of an item type in a box,
+// incrementing the quantity when the type is already there
+func (p *Profile) AddItem(roomName, boxID string, item Item) error {
+	i, j, err := p.locateBox(roomName, boxID)
+	if err != nil {
+		return err
+	}
+	box := &p.Inventory[i].Boxes[j]
+	k, err := box.ItemIndex(item.Type)
+	if err != nil {
+		box.Items = append(box.Items, item)
+		return nil
+	}
+	box.Items[k].Quantity += item.Quantity
+	return nil
+}
+
+// SetItemQuantity overrides the quantity of an item already in a box
+func (p *Profile) SetItemQuantity(roomName, boxID, itemType string, quantity int) error {
+	i, j, err := p.locateBox(roomName, boxID)
+	if err != nil {
+		return err
+	}
+	box := &p.Inventory[i].Boxes[j]
+	k, err := box.ItemIndex(itemType)
+	if err != nil {
+		return err
+	}
+	box.Items[k].Quantity = quantity
+	return nil
+}
+
+// RemoveItem takes an item type out of a box
+func (p *Profile) RemoveItem(roomName, boxID, itemType string) error {
+	i, j, err := p.locateBox(roomName, boxID)
+	if err != nil {
+		return err
+	}
+	box := &p.Inventory[i].Boxes[j]
+	k, err := box.ItemIndex(itemType)
+	if err != nil {
+		return err
+	}
+	box.Items = append(box.Items[:k], box.Items[k+1:]...)
+	return nil
+}
+
+func (p *Profile) locateBox(roomName, boxID string) (i, j int, err error) {
+	i, err = p.RoomIndex(roomName)
+	if err != nil {
+		return
+	}
+	j, err = p.Inventory[i].BoxIndex(boxID)
+	return
+}
+
+// Clone returns a deep copy of the profile so it can
+// be changed without touching the original inventory
+func (p Profile) Clone() Profile {
+	rooms := make([]Room, len(p.Inventory))
+	for i, room := range p.Inventory {
+		boxes := make([]Box, len(room.Boxes))
+		for j, box := range room.Boxes {
+			box.Items = append([]Item{}, box.Items...)
+			boxes[j] = box
+		}
+		room.Boxes = boxes
+		rooms[i] = room
+	}
+	p.Inventory = rooms
+	if p.Booking != nil {
+		booking := *p.Booking
+		p.Booking = &booking
+	}
+	p.Stops = append([]Stop(nil), p.Stops...)
+	p.Offer.Timeline = append([]StatusChange(nil), p.Offer.Timeline...)
+	p.Offer.Breakdown = append([]LineItem(nil), p.Offer.Breakdown...)
+	if p.Offer.Cancellation != nil {
+		cancellation := *p.Offer.Cancellation
+		p.Offer.Cancellation = &cancellation
+	}
+	p.PreviousOffers = append([]Offer(nil), p.PreviousOffers...)
+	return p
+}
@@ -0,0 +1,67 @@
+package models
+
+import "testing"
+
+func TestAssignBoxIDs(t *testing.T) {
+	p := Profile{Inventory: []Room{
+		{Name: "sala", Boxes: []Box{{}, {ID: "kept", Number: 7, Label: "tv"}, {Label: "livros"}}},
+		{Name: "quarto", Boxes: []Box{{Number: 3}}},
+	}}
+	if assigned := p.AssignBoxIDs(); assigned != 3 {
+		t.Errorf("assigned %d IDs, want 3", assigned)
+	}
+	tests := []struct {
+		room, box int
+		number    int
+		label     string
+	}{
+		{room: 0, box: 0, number: 1, label: "sala #1"},
+		{room: 0, box: 1, number: 7, label: "tv"},
+		{room: 0, box: 2, number: 3, label: "livros"},
+		{room: 1, box: 0, number: 3, label: "quarto #3"},
+	}
+	seen := make(map[string]bool)
+	for _, tt := range tests {
+		box := p.Inventory[tt.room].Boxes[tt.box]
+		if box.ID == "" || seen[box.ID] || box.Number != tt.number || box.Label != tt.label {
+			t.Errorf("box %d of %s = %+v", tt.box, p.Inventory[tt.room].Name, box)
+		}
+		seen[box.ID] = true
+	}
+	if p.Inventory[0].Boxes[1].ID != "kept" {
+		t.Errorf("box ID changed to %s", p.Inventory[0].Boxes[1].ID)
+	}
+	if assigned := p.AssignBoxIDs(); assigned != 0 {
+		t.Errorf("assigned %d IDs again", assigned)
+	}
+}
+
+func TestBoxIndex(t *testing.T) {
+	room := Room{Name: "sala", Boxes: []Box{{ID: "a", Number: 1}, {ID: "2", Number: 5}, {ID: "c", Number: 2}}}
+	tests := []struct {
+		ref   string
+		index int
+	}{
+		{ref: "a", index: 0},
+		{ref: "c", index: 2},
+		// IDs come before numbers
+		{ref: "2", index: 1},
+		{ref: "5", index: 1},
+		{ref: "9", index: -1},
+		{ref: "x", index: -1},
+	}
+	for _, tt := range tests {
+		index, err := room.BoxIndex(tt.ref)
+		if index != tt.index || (err == nil) != (tt.index >= 0) {
+			t.Errorf("BoxIndex(%q) = %d, %v, want %d", tt.ref, index, err, tt.index)
+		}
+	}
+}
+
+func TestNewBoxNumbers(t *testing.T) {
+	room := Room{Name: "sala", Boxes: []Box{{Number: 1}, {Number: 4}}}
+	box := NewBox(room, nil)
+	if box.ID == "" || box.Number != 5 || box.Label != "sala #5" || box.Items == nil {
+		t.Errorf("box %+v", box)
+	}
+}
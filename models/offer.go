@@ -0,0 +1,265 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// OfferStatus is the stage of the offer in its lifecycle
+type OfferStatus string
+
+const (
+	OfferDraft     OfferStatus = "draft"
+	OfferQuoted    OfferStatus = "quoted"
+	OfferAccepted  OfferStatus = "accepted"
+	OfferPaid      OfferStatus = "paid"
+	OfferCompleted OfferStatus = "completed"
+	OfferCancelled OfferStatus = "cancelled"
+)
+
+var (
+	ErrInvalidTransition = errors.New("Offer cannot go to that status from its current one")
+	ErrQuoteExpired      = errors.New("Quote has expired, price the move again")
+	ErrQuoteReplaced     = errors.New("Quote was replaced by a newer one")
+	ErrQuoteOtherDate    = errors.New("Quote was priced for another moving date, price the move again")
+	ErrOfferChanged      = errors.New("Offer was changed concurrently, read it again")
+)
+
+// transitions lists the statuses an offer may go to from each status.
+// Quoting again replaces the offer, which is kept in the history
+var transitions = map[OfferStatus][]OfferStatus{
+	OfferDraft:     {OfferQuoted},
+	OfferQuoted:    {OfferQuoted, OfferAccepted, OfferCancelled},
+	OfferAccepted:  {OfferPaid, OfferCancelled},
+	OfferPaid:      {OfferCompleted, OfferCancelled},
+	OfferCompleted: {},
+	OfferCancelled: {OfferQuoted},
+}
+
+// lockedStatuses are the statuses of accepted offers, whose
+// inventory and stops cannot change until they are cancelled
+var lockedStatuses = []OfferStatus{OfferAccepted, OfferPaid, OfferCompleted}
+
+// StatusChange records when the offer reached a status
+type StatusChange struct {
+	Status OfferStatus `bson:"status" json:"status"`
+	At     time.Time   `bson:"at" json:"em"`
+}
+
+type Offer struct {
+	VehicleType      int     `bson:"vehicle" json:"veiculo"`
+	KmValue          float64 `bson:"km_value" json:"valor_por_km"`
+	Distance         float64 `bson:"distance" json:"distancia"`
+	LabourValue      float64 `bson:"labour_value" json:"mao_de_obra"`
+	TotalValue       float64 `bson:"total_value" json:"total_value"`
+	TariffVersion    int     `bson:"tariff_version" json:"versao_tarifa"`
+	Load             Load    `bson:"load" json:"carga"`
+	Trips            int     `bson:"trips" json:"viagens"`
+	SuggestedVehicle int     `bson:"suggested_vehicle,omitempty" json:"veiculo_sugerido,omitempty"`
+	Warning          string  `bson:"warning,omitempty" json:"aviso,omitempty"`
+
+	Breakdown []LineItem `bson:"breakdown,omitempty" json:"detalhamento,omitempty"`
+	TaxRate   float64    `bson:"tax_rate,omitempty" json:"imposto_percentual,omitempty"`
+	// Date is the moving day the offer was priced for
+	Date string `bson:"date,omitempty" json:"data_mudanca,omitempty"`
+	// Coupon is the promo code discounted from the price
+	Coupon string `bson:"coupon,omitempty" json:"cupom,omitempty"`
+	// Payment is the charge that paid the offer
+	Payment      string        `bson:"payment,omitempty" json:"pagamento,omitempty"`
+	Cancellation *Cancellation `bson:"cancellation,omitempty" json:"cancelamento,omitempty"`
+
+	ID string `bson:"id,omitempty" json:"id,omitempty"`
+	// ValidUntil is when the quote expires, nil until quoted
+	ValidUntil *time.Time `bson:"valid_until,omitempty" json:"valido_ate,omitempty"`
+	// Revision counts the saves of the offer and the changes of
+	// the inventory it prices, so a save made from a stale read
+	// can be told apart and refused
+	Revision int `bson:"revision,omitempty" json:"-"`
+
+	Status   OfferStatus    `bson:"status,omitempty" json:"status"`
+	Timeline []StatusChange `bson:"timeline,omitempty" json:"historico,omitempty"`
+}
+
+// CheckCapacity works out how many trips the vehicle needs
+// for the load and, when it is more than one, warns about it
+// and suggests the smallest vehicle that needs a single trip
+func (o *Offer) CheckCapacity(t Tariff, l Load) error {
+	rate, err := t.Rate(o.VehicleType)
+	if err != nil {
+		return err
+	}
+	o.Load = l
+	o.Trips = rate.Trips(l)
+	o.SuggestedVehicle = 0
+	o.Warning = ""
+	if o.Trips == 1 {
+		return nil
+	}
+	o.Warning = fmt.Sprintf("Vehicle type %d needs %d trips to carry %.2f m³ and %.0f kg", o.VehicleType, o.Trips, l.Volume, l.Weight)
+	if bigger, ok := t.SmallestFitting(l); ok {
+		o.SuggestedVehicle = bigger.VehicleType
+		o.Warning += fmt.Sprintf(", vehicle type %d carries it in one trip", bigger.VehicleType)
+	}
+	return nil
+}
+
+// CalculateTotalValue prices the offer with the rates of
+// its vehicle type in the given tariff. Distance is the whole
+// route through the stops, and every extra trip drives it back
+// and forth, so it is charged twice the distance.
+// Each part of the price is a line of the breakdown
+func (o *Offer) CalculateTotalValue(t Tariff, m Move) error {
+	rate, err := t.Rate(o.VehicleType)
+	if err != nil {
+		return err
+	}
+	if o.Trips < 1 {
+		o.Trips = 1
+	}
+	o.LabourValue = rate.LabourValue
+	o.KmValue = rate.KmValue
+	o.TariffVersion = t.Version
+	o.TaxRate = t.TaxRate
+	o.Breakdown = nil
+	o.AddLine(newLineItem(LineLabour, fmt.Sprintf("Mão de obra, veículo %d", o.VehicleType), 1, rate.LabourValue))
+	distance := fmt.Sprintf("Distância: %.1f km", o.Distance)
+	if len(m.Stops) > 0 {
+		distance += fmt.Sprintf(" em %d trechos", len(m.Stops)+1)
+	}
+	if o.Trips > 1 {
+		distance += fmt.Sprintf(", %d viagens", o.Trips)
+	}
+	km := math.Round(o.Distance*float64(2*o.Trips-1)*10) / 10
+	o.AddLine(newLineItem(LineDistance, distance, km, rate.KmValue))
+	for _, item := range t.Surcharges.For("na origem", m.From) {
+		o.AddLine(item)
+	}
+	for i, stop := range m.Stops {
+		for _, item := range t.Surcharges.For(fmt.Sprintf("na parada %d", i+1), stop) {
+			o.AddLine(item)
+		}
+	}
+	for _, item := range t.Surcharges.For("no destino", m.To) {
+		o.AddLine(item)
+	}
+	for _, item := range t.Surcharges.Stops(m) {
+		o.AddLine(item)
+	}
+	for _, item := range t.Services.For(m) {
+		o.AddLine(item)
+	}
+	for _, item := range t.Demand.For(m, o.VehicleType, o.undiscounted()) {
+		o.AddLine(item)
+	}
+	o.Date = ""
+	if !m.Date.IsZero() {
+		o.Date = m.Date.Format(DateLayout)
+	}
+	return nil
+}
+
+// CurrentStatus is the status of the offer, draft
+// for offers that were never priced
+func (o *Offer) CurrentStatus() OfferStatus {
+	if o.Status == "" {
+		return OfferDraft
+	}
+	return o.Status
+}
+
+// Locked tells whether the offer was accepted, locking the
+// inventory and stops it was priced with
+func (o *Offer) Locked() bool {
+	for _, status := range lockedStatuses {
+		if o.CurrentStatus() == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CanGo tells whether the offer may go to the status
+func (o *Offer) CanGo(to OfferStatus) bool {
+	for _, allowed := range transitions[o.CurrentStatus()] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the offer to the status, recording when
+func (o *Offer) Transition(to OfferStatus, at time.Time) error {
+	if !o.CanGo(to) {
+		return ErrInvalidTransition
+	}
+	o.Status = to
+	o.Timeline = append(o.Timeline, StatusChange{Status: to, At: at})
+	return nil
+}
+
+// Expired tells whether the price can no longer be accepted.
+// Offers priced before quotes had a validity never expire
+// once accepted, but cannot be accepted
+func (o *Offer) Expired(at time.Time) bool {
+	return o.ValidUntil == nil || at.After(*o.ValidUntil)
+}
+
+// Expire ends the quote at the given time, when the move
+// it priced changed and it must be priced again
+func (o *Offer) Expire(at time.Time) {
+	if o.CurrentStatus() == OfferQuoted && !o.Expired(at) {
+		o.ValidUntil = &at
+	}
+}
+
+// CanAccept checks the quote can be accepted at its locked
+// price. When quoteID is given it must be the current quote,
+// so users do not accept a price they have not seen
+func (o *Offer) CanAccept(quoteID string, at time.Time) error {
+	if !o.CanGo(OfferAccepted) {
+		return ErrInvalidTransition
+	}
+	if quoteID != "" && quoteID != o.ID {
+		return ErrQuoteReplaced
+	}
+	if o.Expired(at) {
+		return ErrQuoteExpired
+	}
+	return nil
+}
+
+// Accept accepts the quote at its locked price
+func (o *Offer) Accept(quoteID string, at time.Time) error {
+	if err := o.CanAccept(quoteID, at); err != nil {
+		return err
+	}
+	return o.Transition(OfferAccepted, at)
+}
+
+// Quote makes the priced offer the current one, valid for the
+// given time, keeping the offer it replaces in the history
+// when it had been priced. Offers priced before the lifecycle
+// have no status, but do have a value
+func (p *Profile) Quote(o Offer, at time.Time, validity time.Duration) error {
+	if !p.Offer.CanGo(OfferQuoted) {
+		return ErrInvalidTransition
+	}
+	if p.Offer.Status != "" || p.Offer.TotalValue > 0 {
+		p.PreviousOffers = append(p.PreviousOffers, p.Offer)
+	}
+	o.ID = bson.NewObjectId().Hex()
+	validUntil := at.Add(validity)
+	o.ValidUntil = &validUntil
+	o.Status = OfferDraft
+	o.Timeline = nil
+	if err := o.Transition(OfferQuoted, at); err != nil {
+		return err
+	}
+	p.Offer = o
+	return nil
+}
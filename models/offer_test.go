@@ -0,0 +1,187 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransitions(t *testing.T) {
+	statuses := []OfferStatus{OfferDraft, OfferQuoted, OfferAccepted, OfferPaid, OfferCompleted, OfferCancelled}
+	allowed := map[OfferStatus][]OfferStatus{
+		OfferDraft:     {OfferQuoted},
+		OfferQuoted:    {OfferQuoted, OfferAccepted, OfferCancelled},
+		OfferAccepted:  {OfferPaid, OfferCancelled},
+		OfferPaid:      {OfferCompleted, OfferCancelled},
+		OfferCompleted: {},
+		OfferCancelled: {OfferQuoted},
+	}
+	at := time.Date(2030, 4, 1, 12, 0, 0, 0, time.UTC)
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := false
+			for _, s := range allowed[from] {
+				want = want || s == to
+			}
+			o := Offer{Status: from}
+			err := o.Transition(to, at)
+			if want && err != nil {
+				t.Errorf("%s -> %s: err = %v", from, to, err)
+			}
+			if !want && err != ErrInvalidTransition {
+				t.Errorf("%s -> %s: err = %v, want %v", from, to, err, ErrInvalidTransition)
+			}
+			if !want {
+				continue
+			}
+			if o.Status != to || len(o.Timeline) != 1 || o.Timeline[0] != (StatusChange{Status: to, At: at}) {
+				t.Errorf("%s -> %s: offer %+v", from, to, o)
+			}
+		}
+	}
+}
+
+func TestUnpricedOfferIsDraft(t *testing.T) {
+	o := Offer{}
+	if got := o.CurrentStatus(); got != OfferDraft {
+		t.Errorf("status = %s, want %s", got, OfferDraft)
+	}
+	if !o.CanGo(OfferQuoted) || o.CanGo(OfferAccepted) {
+		t.Error("offers never priced should only be quoted")
+	}
+}
+
+func TestCanAccept(t *testing.T) {
+	at := time.Date(2030, 4, 1, 12, 0, 0, 0, time.UTC)
+	later := at.Add(time.Hour)
+	tests := []struct {
+		name    string
+		offer   Offer
+		quoteID string
+		err     error
+	}{
+		{name: "valid quote", offer: Offer{ID: "q1", Status: OfferQuoted, ValidUntil: &later}},
+		{name: "named quote", offer: Offer{ID: "q1", Status: OfferQuoted, ValidUntil: &later}, quoteID: "q1"},
+		{name: "replaced quote", offer: Offer{ID: "q2", Status: OfferQuoted, ValidUntil: &later}, quoteID: "q1", err: ErrQuoteReplaced},
+		{name: "expired quote", offer: Offer{ID: "q1", Status: OfferQuoted, ValidUntil: &at}, err: ErrQuoteExpired},
+		{name: "quote without validity", offer: Offer{ID: "q1", Status: OfferQuoted}, err: ErrQuoteExpired},
+		{name: "accepted", offer: Offer{ID: "q1", Status: OfferAccepted, ValidUntil: &later}, err: ErrInvalidTransition},
+		{name: "draft", offer: Offer{}, err: ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.offer.CanAccept(tt.quoteID, at.Add(time.Minute)); err != tt.err {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestExpire(t *testing.T) {
+	at := time.Date(2030, 4, 1, 12, 0, 0, 0, time.UTC)
+	later := at.Add(time.Hour)
+	tests := []struct {
+		status  OfferStatus
+		expired bool
+	}{
+		{OfferQuoted, true},
+		{OfferAccepted, false},
+		{OfferPaid, false},
+	}
+	for _, tt := range tests {
+		valid := later
+		o := Offer{Status: tt.status, ValidUntil: &valid}
+		o.Expire(at)
+		if got := o.Expired(at.Add(time.Second)); got != tt.expired {
+			t.Errorf("%s offer expired = %v, want %v", tt.status, got, tt.expired)
+		}
+	}
+}
+
+func TestQuoteKeepsHistory(t *testing.T) {
+	at := time.Date(2030, 4, 1, 12, 0, 0, 0, time.UTC)
+	p := Profile{}
+	if err := p.Quote(Offer{VehicleType: 1}, at, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	first := p.Offer
+	if first.ID == "" || first.CurrentStatus() != OfferQuoted || !first.ValidUntil.Equal(at.Add(time.Hour)) {
+		t.Fatalf("quote %+v", first)
+	}
+	if len(p.PreviousOffers) != 0 {
+		t.Errorf("draft offer went to the history")
+	}
+	if err := p.Quote(Offer{VehicleType: 2}, at, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.PreviousOffers) != 1 || p.PreviousOffers[0].ID != first.ID || p.Offer.ID == first.ID {
+		t.Errorf("history %+v after quoting again", p.PreviousOffers)
+	}
+	p.Offer.Status = OfferAccepted
+	if err := p.Quote(Offer{VehicleType: 3}, at, time.Hour); err != ErrInvalidTransition {
+		t.Errorf("err = %v quoting an accepted offer, want %v", err, ErrInvalidTransition)
+	}
+}
+
+func TestQuoteKeepsLegacyOffer(t *testing.T) {
+	at := time.Date(2030, 4, 1, 12, 0, 0, 0, time.UTC)
+	// priced before offers had a status
+	p := Profile{Offer: Offer{VehicleType: 1, TotalValue: 350}}
+	if err := p.Quote(Offer{VehicleType: 2}, at, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.PreviousOffers) != 1 || p.PreviousOffers[0].TotalValue != 350 {
+		t.Errorf("history %+v after quoting a legacy offer again", p.PreviousOffers)
+	}
+}
+
+func TestSaveOfferRefusesStaleReads(t *testing.T) {
+	r := NewMemoryRepository()
+	p := Profile{Offer: Offer{ID: "q1", Status: OfferQuoted}}
+	if err := r.Create(&p); err != nil {
+		t.Fatal(err)
+	}
+	// an offer priced before an inventory change is stale
+	stale, _ := r.GetByID(p.ID)
+	if err := r.AddRoom(p.ID, Room{Name: "sala"}); err != nil {
+		t.Fatal(err)
+	}
+	previous := stale.Offer
+	stale.Offer.Coupon = "DEZ"
+	if err := r.SaveOffer(&stale, previous); err != ErrOfferChanged {
+		t.Fatalf("err = %v saving an offer read before an inventory change, want %v", err, ErrOfferChanged)
+	}
+
+	first, _ := r.GetByID(p.ID)
+	second, _ := r.GetByID(p.ID)
+	previous = first.Offer
+	if err := first.Offer.Transition(OfferAccepted, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SaveOffer(&first, previous); err != nil {
+		t.Fatal(err)
+	}
+	previous = second.Offer
+	if err := second.Offer.Transition(OfferCancelled, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SaveOffer(&second, previous); err != ErrOfferChanged {
+		t.Fatalf("err = %v saving a stale offer, want %v", err, ErrOfferChanged)
+	}
+
+	// and the inventory of the accepted offer is locked
+	if err := r.AddRoom(p.ID, Room{Name: "quarto"}); err != ErrInventoryLocked {
+		t.Fatalf("err = %v changing a locked inventory, want %v", err, ErrInventoryLocked)
+	}
+	previous = first.Offer
+	first.Offer.Coupon = "DEZ"
+	if err := r.SaveOffer(&first, previous); err != nil {
+		t.Fatalf("err = %v saving the current offer", err)
+	}
+	stored, _ := r.GetByID(p.ID)
+	if stored.Offer.CurrentStatus() != OfferAccepted || stored.Offer.Coupon != "DEZ" || len(stored.Inventory) != 1 {
+		t.Errorf("stored profile %+v", stored)
+	}
+	if err := r.SaveOffer(&Profile{ID: 99}, Offer{}); err != ErrProfileNotFound {
+		t.Errorf("err = %v, want %v", err, ErrProfileNotFound)
+	}
+}
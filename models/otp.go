@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrOTPNotFound = errors.New("No pending code for this phone number")
+	ErrOTPLimited  = errors.New("Too many codes asked for this phone number, wait before asking for another")
+)
+
+// OTP is a one time password sent by SMS, stored hashed.
+// The record outlives its code until ResetAt, so that the codes
+// sent and the attempts made count across re-issued codes
+type OTP struct {
+	Phone     string    `bson:"_id"`
+	CodeHash  string    `bson:"code_hash"`
+	SentAt    time.Time `bson:"sent_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+	Attempts  int       `bson:"attempts"`
+	Sends     int       `bson:"sends"`
+	ResetAt   time.Time `bson:"reset_at"`
+}
+
+// OTPLimits bounds how often a phone number gets codes
+type OTPLimits struct {
+	// ResendAfter is the least time between two codes
+	ResendAfter time.Duration
+	// Window is how long the budget lasts from the first code
+	Window time.Duration
+	// MaxSends is the number of codes sent in a window
+	MaxSends int
+}
+
+// OTPRepository stores the pending codes, one per phone number
+type OTPRepository interface {
+	Get(phone string) (OTP, error)
+	// Issue replaces the pending code of the phone number with the
+	// code hash, sent and expiry times of otp. The sends and attempts
+	// counted in the window carry over, both start again once it is
+	// over. It fails with ErrOTPLimited, atomically, when the last
+	// code is younger than ResendAfter or MaxSends were sent
+	Issue(otp OTP, limits OTPLimits) error
+	// Attempt counts a verification attempt and returns the
+	// code as it was before it, atomically
+	Attempt(phone string) (OTP, error)
+	Delete(phone string) error
+}
@@ -0,0 +1,62 @@
+package models
+
+import "sync"
+
+// MemoryOTPRepository keeps pending codes in a map
+type MemoryOTPRepository struct {
+	mu   sync.Mutex
+	otps map[string]OTP
+}
+
+func NewMemoryOTPRepository() *MemoryOTPRepository {
+	return &MemoryOTPRepository{otps: make(map[string]OTP)}
+}
+
+func (r *MemoryOTPRepository) Get(phone string) (OTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	otp, ok := r.otps[phone]
+	if !ok {
+		return otp, ErrOTPNotFound
+	}
+	return otp, nil
+}
+
+func (r *MemoryOTPRepository) Issue(otp OTP, limits OTPLimits) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pending, ok := r.otps[otp.Phone]
+	if ok && pending.ResetAt.After(otp.SentAt) {
+		if otp.SentAt.Sub(pending.SentAt) < limits.ResendAfter || pending.Sends >= limits.MaxSends {
+			return ErrOTPLimited
+		}
+		otp.Attempts, otp.Sends, otp.ResetAt = pending.Attempts, pending.Sends+1, pending.ResetAt
+	} else {
+		otp.Attempts, otp.Sends, otp.ResetAt = 0, 1, otp.SentAt.Add(limits.Window)
+	}
+	r.otps[otp.Phone] = otp
+	return nil
+}
+
+func (r *MemoryOTPRepository) Attempt(phone string) (OTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	otp, ok := r.otps[phone]
+	if !ok {
+		return otp, ErrOTPNotFound
+	}
+	updated := otp
+	updated.Attempts++
+	r.otps[phone] = updated
+	return otp, nil
+}
+
+func (r *MemoryOTPRepository) Delete(phone string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.otps[phone]; !ok {
+		return ErrOTPNotFound
+	}
+	delete(r.otps, phone)
+	return nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MongoOTPRepository stores codes in the otps collection,
+// which MongoDB purges once their window is over
+type MongoOTPRepository struct {
+	DB *mgo.Database
+}
+
+func NewMongoOTPRepository(db *mgo.Database) *MongoOTPRepository {
+	return &MongoOTPRepository{DB: db}
+}
+
+func (r *MongoOTPRepository) otps() *mgo.Collection {
+	return r.DB.C("otps")
+}
+
+// EnsureIndexes creates the TTL index dropping codes whose window
+// is over. The index on expires_at dropped them with their code,
+// forgetting the sends and attempts counted, so it is removed
+func (r *MongoOTPRepository) EnsureIndexes() error {
+	if err := r.otps().DropIndex("expires_at"); err != nil && !isIndexNotFound(err) {
+		return err
+	}
+	return r.otps().EnsureIndex(mgo.Index{Key: []string{"reset_at"}, ExpireAfter: time.Second})
+}
+
+// isIndexNotFound tells the errors of dropping an index, or
+// one of a collection, that does not exist
+func isIndexNotFound(err error) bool {
+	qerr, ok := err.(*mgo.QueryError)
+	return ok && (qerr.Code == 26 || qerr.Code == 27)
+}
+
+func (r *MongoOTPRepository) Get(phone string) (otp OTP, err error) {
+	err = r.otps().FindId(phone).One(&otp)
+	if err == mgo.ErrNotFound {
+		err = ErrOTPNotFound
+	}
+	return
+}
+
+// Issue first counts the code in the running window, when the
+// last one is old enough and the budget is not spent. Failing that
+// it starts a window with an upsert matching only a missing or
+// finished window, or a code saved before windows, so a running
+// one makes the insert fail on the duplicate _id instead of
+// resetting the budget
+func (r *MongoOTPRepository) Issue(otp OTP, limits OTPLimits) error {
+	code := bson.M{"code_hash": otp.CodeHash, "sent_at": otp.SentAt, "expires_at": otp.ExpiresAt}
+	err := r.otps().Update(bson.M{
+		"_id":      otp.Phone,
+		"reset_at": bson.M{"$gt": otp.SentAt},
+		"sent_at":  bson.M{"$lte": otp.SentAt.Add(-limits.ResendAfter)},
+		"sends":    bson.M{"$lt": limits.MaxSends},
+	}, bson.M{"$set": code, "$inc": bson.M{"sends": 1}})
+	if err != mgo.ErrNotFound {
+		return err
+	}
+	code["attempts"], code["sends"], code["reset_at"] = 0, 1, otp.SentAt.Add(limits.Window)
+	_, err = r.otps().Upsert(bson.M{"_id": otp.Phone, "$or": []bson.M{
+		{"reset_at": bson.M{"$lte": otp.SentAt}},
+		{"reset_at": bson.M{"$exists": false}},
+	}}, bson.M{"$set": code})
+	if mgo.IsDup(err) {
+		err = ErrOTPLimited
+	}
+	return err
+}
+
+func (r *MongoOTPRepository) Attempt(phone string) (otp OTP, err error) {
+	_, err = r.otps().FindId(phone).Apply(mgo.Change{Update: bson.M{"$inc": bson.M{"attempts": 1}}}, &otp)
+	if err == mgo.ErrNotFound {
+		err = ErrOTPNotFound
+	}
+	return
+}
+
+func (r *MongoOTPRepository) Delete(phone string) (err error) {
+	err = r.otps().RemoveId(phone)
+	if err == mgo.ErrNotFound {
+		err = ErrOTPNotFound
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryOTPIssue(t *testing.T) {
+	r := NewMemoryOTPRepository()
+	limits := OTPLimits{ResendAfter: 30 * time.Second, Window: time.Hour, MaxSends: 3}
+	start := time.Date(2030, 4, 1, 12, 0, 0, 0, time.UTC)
+	issue := func(after time.Duration, hash string) error {
+		sent := start.Add(after)
+		return r.Issue(OTP{Phone: "+5521999999999", CodeHash: hash, SentAt: sent, ExpiresAt: sent.Add(5 * time.Minute)}, limits)
+	}
+
+	if err := issue(0, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Attempt("+5521999999999"); err != nil {
+		t.Fatal(err)
+	}
+	if err := issue(10*time.Second, "b"); err != ErrOTPLimited {
+		t.Errorf("resent too soon: %v", err)
+	}
+	if err := issue(time.Minute, "b"); err != nil {
+		t.Fatal(err)
+	}
+	otp, err := r.Get("+5521999999999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otp.CodeHash != "b" || otp.Attempts != 1 || otp.Sends != 2 || !otp.ResetAt.Equal(start.Add(time.Hour)) {
+		t.Errorf("re-issued %+v, want code b with the attempt and window carried over", otp)
+	}
+	if err := issue(2*time.Minute, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := issue(3*time.Minute, "d"); err != ErrOTPLimited {
+		t.Errorf("past the budget: %v", err)
+	}
+
+	// the budget starts again once the window is over
+	if err := issue(time.Hour, "e"); err != nil {
+		t.Fatal(err)
+	}
+	if otp, _ = r.Get("+5521999999999"); otp.CodeHash != "e" || otp.Attempts != 0 || otp.Sends != 1 {
+		t.Errorf("new window %+v", otp)
+	}
+}
+
+func TestMemoryOTPIssueConcurrently(t *testing.T) {
+	r := NewMemoryOTPRepository()
+	limits := OTPLimits{ResendAfter: 30 * time.Second, Window: time.Hour, MaxSends: 5}
+	now := time.Now()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	issued := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Issue(OTP{Phone: "+5521999999999", SentAt: now, ExpiresAt: now.Add(5 * time.Minute)}, limits); err == nil {
+				mu.Lock()
+				issued++
+				mu.Unlock()
+			} else if err != ErrOTPLimited {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if issued != 1 {
+		t.Errorf("%d codes issued at once, want 1", issued)
+	}
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrPaymentNotFound = errors.New("Payment not found")
+	ErrPaymentExists   = errors.New("Payment already exists")
+	ErrRefundChanged   = errors.New("Refund was changed concurrently, read it again")
+)
+
+// PaymentStatus is the outcome of a charge at the provider
+type PaymentStatus string
+
+const (
+	PaymentPending  PaymentStatus = "pending"
+	PaymentPaid     PaymentStatus = "paid"
+	PaymentFailed   PaymentStatus = "failed"
+	PaymentRefunded PaymentStatus = "refunded"
+)
+
+// paymentOrder ranks the statuses a charge goes through, so
+// an event delivered late cannot take a payment back
+var paymentOrder = map[PaymentStatus]int{
+	PaymentPending:  0,
+	PaymentFailed:   1,
+	PaymentPaid:     2,
+	PaymentRefunded: 3,
+}
+
+// Precedes tells whether a payment may still go from the status
+// to the other one. A failed charge may be paid on a retry, but
+// a failure reported after the payment is ignored
+func (s PaymentStatus) Precedes(to PaymentStatus) bool {
+	return paymentOrder[s] < paymentOrder[to]
+}
+
+// preceding lists the statuses that may go to the status
+func (s PaymentStatus) preceding() []PaymentStatus {
+	var statuses []PaymentStatus
+	for status := range paymentOrder {
+		if status.Precedes(s) {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// Payment is a charge created to collect the price of a quote,
+// identified by the ID the payment provider gave it
+type Payment struct {
+	ID          string        `bson:"_id" json:"id"`
+	Profile     int           `bson:"profile" json:"numero"`
+	QuoteID     string        `bson:"quote" json:"cotacao"`
+	Method      string        `bson:"method" json:"metodo"`
+	Amount      float64       `bson:"amount" json:"valor"`
+	Status      PaymentStatus `bson:"status" json:"status"`
+	CheckoutURL string        `bson:"checkout_url,omitempty" json:"url,omitempty"`
+	PixCode     string        `bson:"pix_code,omitempty" json:"pix_copia_e_cola,omitempty"`
+	// ExpiresAt is when the provider stops taking the charge,
+	// nil for charges that do not expire
+	ExpiresAt *time.Time `bson:"expires_at,omitempty" json:"expira_em,omitempty"`
+	// Static marks the static Pix code of the quote, which is
+	// paid by transfer rather than through the provider
+	Static    bool      `bson:"static,omitempty" json:"estatico,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"criado_em"`
+	UpdatedAt time.Time `bson:"updated_at" json:"atualizado_em"`
+	// Events are the provider callbacks already applied
+	Events []string `bson:"events" json:"-"`
+	// RefundStatus follows the refund of a charge paid for an
+	// offer that did not take it, recorded before the charge is
+	// applied so the refund is never forgotten
+	RefundStatus RefundStatus `bson:"refund_status,omitempty" json:"reembolso_status,omitempty"`
+	RefundID     string       `bson:"refund_id,omitempty" json:"reembolso_id,omitempty"`
+	// RefundError is why the provider refused the refund
+	RefundError string `bson:"refund_error,omitempty" json:"reembolso_erro,omitempty"`
+}
+
+// PaymentRepository stores the payments. Apply records each
+// provider event once, so duplicate callbacks are ignored
+type PaymentRepository interface {
+	// Create fails with ErrPaymentExists when the ID is taken
+	Create(p Payment) error
+	Get(id string) (Payment, error)
+	ForQuote(quoteID string) ([]Payment, error)
+	// Apply sets the status of the payment unless the event was
+	// applied before or the payment is already past the status,
+	// telling whether it was applied now
+	Apply(id, event string, status PaymentStatus, at time.Time) (bool, error)
+	// SetRefund records the refund of the payment as long as its
+	// refund status is still one of from, where "" stands for no
+	// refund, failing with ErrRefundChanged otherwise
+	SetRefund(id string, from []RefundStatus, to RefundStatus, refundID, refundError string) error
+	// RefundsDue lists the payments whose refund is still to be made
+	RefundsDue() ([]Payment, error)
+}
+
+// RefundDue tells whether the charge is still to be refunded
+func (p *Payment) RefundDue() bool {
+	return p.RefundStatus == RefundPending || p.RefundStatus == RefundFailed
+}
+
+// Charged tells whether the amount is the one of the charge
+func (p *Payment) Charged(amount float64) bool {
+	return roundCents(amount) == roundCents(p.Amount)
+}
+
+// Applied tells whether the provider event was applied before
+func (p *Payment) Applied(event string) bool {
+	for _, e := range p.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryPaymentRepository keeps payments in a map
+type MemoryPaymentRepository struct {
+	mu       sync.Mutex
+	payments map[string]Payment
+}
+
+func NewMemoryPaymentRepository() *MemoryPaymentRepository {
+	return &MemoryPaymentRepository{payments: make(map[string]Payment)}
+}
+
+func (r *MemoryPaymentRepository) Create(p Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.payments[p.ID]; ok {
+		return ErrPaymentExists
+	}
+	p.Events = append([]string(nil), p.Events...)
+	r.payments[p.ID] = p
+	return nil
+}
+
+func (r *MemoryPaymentRepository) Get(id string) (Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.payments[id]
+	if !ok {
+		return p, ErrPaymentNotFound
+	}
+	p.Events = append([]string(nil), p.Events...)
+	return p, nil
+}
+
+func (r *MemoryPaymentRepository) ForQuote(quoteID string) ([]Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	payments := []Payment{}
+	for _, p := range r.payments {
+		if p.QuoteID == quoteID {
+			p.Events = append([]string(nil), p.Events...)
+			payments = append(payments, p)
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].CreatedAt.Before(payments[j].CreatedAt) })
+	return payments, nil
+}
+
+func (r *MemoryPaymentRepository) Apply(id, event string, status PaymentStatus, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.payments[id]
+	if !ok {
+		return false, ErrPaymentNotFound
+	}
+	if p.Applied(event) || !p.Status.Precedes(status) {
+		return false, nil
+	}
+	p.Events = append(append([]string(nil), p.Events...), event)
+	p.Status, p.UpdatedAt = status, at
+	r.payments[id] = p
+	return true, nil
+}
+
+func (r *MemoryPaymentRepository) SetRefund(id string, from []RefundStatus, to RefundStatus, refundID, refundError string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.payments[id]
+	if !ok {
+		return ErrPaymentNotFound
+	}
+	for _, status := range from {
+		if p.RefundStatus == status {
+			p.RefundStatus, p.RefundID, p.RefundError = to, refundID, refundError
+			r.payments[id] = p
+			return nil
+		}
+	}
+	return ErrRefundChanged
+}
+
+func (r *MemoryPaymentRepository) RefundsDue() ([]Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	payments := []Payment{}
+	for _, p := range r.payments {
+		if p.RefundDue() {
+			p.Events = append([]string(nil), p.Events...)
+			payments = append(payments, p)
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].CreatedAt.Before(payments[j].CreatedAt) })
+	return payments, nil
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MongoPaymentRepository stores payments in the payments collection
+type MongoPaymentRepository struct {
+	DB *mgo.Database
+}
+
+func NewMongoPaymentRepository(db *mgo.Database) *MongoPaymentRepository {
+	return &MongoPaymentRepository{DB: db}
+}
+
+func (r *MongoPaymentRepository) payments() *mgo.Collection {
+	return r.DB.C("payments")
+}
+
+// EnsureIndexes creates the index finding the payments of a quote
+func (r *MongoPaymentRepository) EnsureIndexes() error {
+	return r.payments().EnsureIndex(mgo.Index{Key: []string{"quote"}})
+}
+
+func (r *MongoPaymentRepository) Create(p Payment) error {
+	if p.Events == nil {
+		p.Events = []string{}
+	}
+	err := r.payments().Insert(&p)
+	if mgo.IsDup(err) {
+		return ErrPaymentExists
+	}
+	return err
+}
+
+func (r *MongoPaymentRepository) Get(id string) (p Payment, err error) {
+	err = r.payments().FindId(id).One(&p)
+	if err == mgo.ErrNotFound {
+		err = ErrPaymentNotFound
+	}
+	return
+}
+
+func (r *MongoPaymentRepository) ForQuote(quoteID string) (payments []Payment, err error) {
+	payments = []Payment{}
+	err = r.payments().Find(bson.M{"quote": quoteID}).Sort("created_at").All(&payments)
+	return
+}
+
+// Apply only matches payments without the event and before the
+// status, so a callback delivered twice at the same time is applied
+// once and events racing each other cannot take the payment back
+func (r *MongoPaymentRepository) Apply(id, event string, status PaymentStatus, at time.Time) (applied bool, err error) {
+	err = r.payments().Update(
+		bson.M{"_id": id, "events": bson.M{"$ne": event}, "status": bson.M{"$in": status.preceding()}},
+		bson.M{"$set": bson.M{"status": status, "updated_at": at}, "$push": bson.M{"events": event}},
+	)
+	if err == mgo.ErrNotFound {
+		_, err = r.Get(id)
+		return false, err
+	}
+	return err == nil, err
+}
+
+// SetRefund matches the refund statuses in the selector, so two
+// refunds made at the same time cannot both ask the provider
+func (r *MongoPaymentRepository) SetRefund(id string, from []RefundStatus, to RefundStatus, refundID, refundError string) error {
+	statuses := []interface{}{}
+	for _, status := range from {
+		if status == "" {
+			statuses = append(statuses, nil, "")
+		} else {
+			statuses = append(statuses, status)
+		}
+	}
+	err := r.payments().Update(
+		bson.M{"_id": id, "refund_status": bson.M{"$in": statuses}},
+		bson.M{"$set": bson.M{"refund_status": to, "refund_id": refundID, "refund_error": refundError}},
+	)
+	if err == mgo.ErrNotFound {
+		if _, err = r.Get(id); err != nil {
+			return err
+		}
+		return ErrRefundChanged
+	}
+	return err
+}
+
+func (r *MongoPaymentRepository) RefundsDue() (payments []Payment, err error) {
+	payments = []Payment{}
+	err = r.payments().Find(bson.M{"refund_status": bson.M{"$in": []RefundStatus{RefundPending, RefundFailed}}}).Sort("created_at").All(&payments)
+	return
+}
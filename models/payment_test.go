@@ -0,0 +1,79 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryPaymentApply(t *testing.T) {
+	r := NewMemoryPaymentRepository()
+	if err := r.Create(Payment{ID: "ch_1", Status: PaymentPending}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(Payment{ID: "ch_1"}); err != ErrPaymentExists {
+		t.Errorf("err = %v, want %v", err, ErrPaymentExists)
+	}
+	at := time.Date(2030, 4, 10, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		event   string
+		status  PaymentStatus
+		applied bool
+		want    PaymentStatus
+	}{
+		{event: "evt_1", status: PaymentFailed, applied: true, want: PaymentFailed},
+		{event: "evt_2", status: PaymentPaid, applied: true, want: PaymentPaid},
+		// delivered again, and a failure reported late
+		{event: "evt_2", status: PaymentPaid, want: PaymentPaid},
+		{event: "evt_3", status: PaymentFailed, want: PaymentPaid},
+		{event: "evt_4", status: PaymentRefunded, applied: true, want: PaymentRefunded},
+	}
+	for _, tt := range tests {
+		applied, err := r.Apply("ch_1", tt.event, tt.status, at)
+		if err != nil || applied != tt.applied {
+			t.Errorf("%s %s: applied %v, %v", tt.event, tt.status, applied, err)
+		}
+		if p, _ := r.Get("ch_1"); p.Status != tt.want {
+			t.Errorf("%s %s: status %s, want %s", tt.event, tt.status, p.Status, tt.want)
+		}
+	}
+	if _, err := r.Apply("ch_2", "evt_5", PaymentPaid, at); err != ErrPaymentNotFound {
+		t.Errorf("err = %v, want %v", err, ErrPaymentNotFound)
+	}
+}
+
+func TestMemoryPaymentRefunds(t *testing.T) {
+	r := NewMemoryPaymentRepository()
+	start := time.Date(2030, 4, 10, 8, 0, 0, 0, time.UTC)
+	for i, id := range []string{"ch_2", "ch_1", "ch_3"} {
+		if err := r.Create(Payment{ID: id, CreatedAt: start.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	due := []RefundStatus{RefundPending, RefundFailed}
+	steps := []struct {
+		id   string
+		from []RefundStatus
+		to   RefundStatus
+		err  error
+	}{
+		{id: "ch_2", from: []RefundStatus{""}, to: RefundPending},
+		{id: "ch_1", from: []RefundStatus{""}, to: RefundPending},
+		{id: "ch_1", from: due, to: RefundRequested},
+		// only one of two refunds at the same time is made
+		{id: "ch_1", from: due, to: RefundRequested, err: ErrRefundChanged},
+		{id: "ch_1", from: []RefundStatus{RefundRequested}, to: RefundFailed},
+		{id: "ch_4", from: due, to: RefundRequested, err: ErrPaymentNotFound},
+	}
+	for _, step := range steps {
+		if err := r.SetRefund(step.id, step.from, step.to, "", ""); err != step.err {
+			t.Errorf("%s to %s: err = %v, want %v", step.id, step.to, err, step.err)
+		}
+	}
+	payments, err := r.RefundsDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 2 || payments[0].ID != "ch_2" || payments[1].ID != "ch_1" || !payments[1].RefundDue() {
+		t.Errorf("refunds due %+v", payments)
+	}
+}
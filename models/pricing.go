@@ -0,0 +1,234 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// LineKind groups the lines of a price breakdown,
+// which are listed in the order of the kinds below
+type LineKind string
+
+const (
+	LineLabour    LineKind = "mao_de_obra"
+	LineDistance  LineKind = "distancia"
+	LineSurcharge LineKind = "acrescimo"
+	LineService   LineKind = "servico"
+	LineDemand    LineKind = "demanda"
+	LineDiscount  LineKind = "desconto"
+	LineTax       LineKind = "imposto"
+)
+
+var lineOrder = map[LineKind]int{
+	LineLabour:    0,
+	LineDistance:  1,
+	LineSurcharge: 2,
+	LineService:   3,
+	LineDemand:    4,
+	LineDiscount:  5,
+	LineTax:       6,
+}
+
+// LineItem is one line of the price of an offer.
+// Discounts have a negative unit price
+type LineItem struct {
+	Kind        LineKind `bson:"kind" json:"tipo"`
+	Description string   `bson:"description" json:"descricao"`
+	Quantity    float64  `bson:"quantity" json:"quantidade"`
+	UnitPrice   float64  `bson:"unit_price" json:"valor_unitario"`
+	Subtotal    float64  `bson:"subtotal" json:"subtotal"`
+}
+
+func newLineItem(kind LineKind, description string, quantity, unitPrice float64) LineItem {
+	return LineItem{
+		Kind:        kind,
+		Description: description,
+		Quantity:    quantity,
+		UnitPrice:   unitPrice,
+		Subtotal:    roundCents(quantity * unitPrice),
+	}
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Move is what the price of an offer depends on besides
+// the vehicle, the distance and the tariff
+type Move struct {
+	From Address
+	To   Address
+	// Stops are the addresses visited between From and To
+	Stops       []Address
+	Load        Load
+	Disassembly int
+	// Date is the moving day, zero when unknown
+	Date time.Time
+	// Utilisation is the share of the slots of each
+	// vehicle type already booked on Date
+	Utilisation map[int]float64
+}
+
+// Move gathers the addresses, moving day and inventory
+// measures of the profile
+func (p *Profile) Move(c Catalog) Move {
+	s := p.Summary(c)
+	m := Move{From: p.CurrentAddress, To: p.NewAddress, Load: s.Load, Disassembly: s.Disassembly}
+	for _, stop := range p.Stops {
+		m.Stops = append(m.Stops, stop.Address)
+	}
+	if !p.MovingData.IsZero() {
+		m.Date = p.MovingData.In(MovingLocation)
+	}
+	return m
+}
+
+// ServiceRates price the work done besides carrying things
+type ServiceRates struct {
+	DisassemblyPerItem float64 `bson:"disassembly_per_item" json:"desmontagem_por_item"`
+}
+
+// For returns the services the move needs
+func (r ServiceRates) For(m Move) (items []LineItem) {
+	if m.Disassembly > 0 && r.DisassemblyPerItem > 0 {
+		items = append(items, newLineItem(LineService, fmt.Sprintf("Desmontagem e montagem: %d itens", m.Disassembly), float64(m.Disassembly), r.DisassemblyPerItem))
+	}
+	return
+}
+
+// DemandRates raise the price of the days most people want to
+// move on. Each rate is a multiplier of the price before
+// discounts and taxes, and zero leaves the rule out. When
+// several rules apply their increases add up
+type DemandRates struct {
+	Weekend float64 `bson:"weekend" json:"fim_de_semana"`
+	// MonthEnd applies to the last MonthEndDays of the month
+	MonthEnd     float64 `bson:"month_end" json:"fim_de_mes"`
+	MonthEndDays int     `bson:"month_end_days" json:"dias_fim_de_mes"`
+	Holiday      float64 `bson:"holiday" json:"feriado"`
+	// Utilisation applies the multiplier of the highest
+	// threshold reached by the booked share of the slots
+	Utilisation []UtilisationRate `bson:"utilisation" json:"ocupacao"`
+}
+
+// UtilisationRate is the multiplier once From (0 to 1)
+// of the slots of the day are booked
+type UtilisationRate struct {
+	From       float64 `bson:"from" json:"a_partir_de"`
+	Multiplier float64 `bson:"multiplier" json:"multiplicador"`
+}
+
+// Validate checks the multipliers and thresholds are sensible
+func (r *DemandRates) Validate() error {
+	multipliers := []float64{r.Weekend, r.MonthEnd, r.Holiday}
+	for _, u := range r.Utilisation {
+		if u.From < 0 || u.From > 1 {
+			return errors.New("Utilisation thresholds go from 0 to 1")
+		}
+		multipliers = append(multipliers, u.Multiplier)
+	}
+	for _, m := range multipliers {
+		if m < 0 || m > 10 {
+			return errors.New("Demand multipliers go from 0 to 10")
+		}
+	}
+	if r.MonthEndDays < 0 || r.MonthEndDays > 28 {
+		return errors.New("Month end days go from 0 to 28")
+	}
+	return nil
+}
+
+// For returns a line for each rule applying to the move
+// with the vehicle, base being the price they multiply
+func (r DemandRates) For(m Move, vehicle int, base float64) (items []LineItem) {
+	if m.Date.IsZero() {
+		return
+	}
+	add := func(description string, multiplier float64) {
+		if multiplier == 0 || multiplier == 1 {
+			return
+		}
+		description += fmt.Sprintf(" (%+g%%)", roundCents((multiplier-1)*100))
+		items = append(items, newLineItem(LineDemand, description, 1, roundCents(base*(multiplier-1))))
+	}
+	if weekday := m.Date.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		add("Fim de semana", r.Weekend)
+	}
+	if r.MonthEndDays > 0 {
+		lastDay := time.Date(m.Date.Year(), m.Date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		if m.Date.Day() > lastDay-r.MonthEndDays {
+			add("Fim de mês", r.MonthEnd)
+		}
+	}
+	if name, ok := Holiday(m.Date); ok {
+		add("Feriado: "+name, r.Holiday)
+	}
+	if u, ok := m.Utilisation[vehicle]; ok && len(r.Utilisation) > 0 {
+		rates := append([]UtilisationRate{}, r.Utilisation...)
+		sort.Slice(rates, func(i, j int) bool { return rates[i].From > rates[j].From })
+		for _, rate := range rates {
+			if u >= rate.From {
+				add(fmt.Sprintf("Alta procura: %.0f%% dos veículos reservados", u*100), rate.Multiplier)
+				break
+			}
+		}
+	}
+	return
+}
+
+// AddLine puts the item in the breakdown after the lines of the
+// same kind and prices the offer again
+func (o *Offer) AddLine(item LineItem) {
+	i := len(o.Breakdown)
+	for i > 0 && lineOrder[o.Breakdown[i-1].Kind] > lineOrder[item.Kind] {
+		i--
+	}
+	o.Breakdown = append(o.Breakdown[:i], append([]LineItem{item}, o.Breakdown[i:]...)...)
+	o.reprice()
+}
+
+// pretax is the sum of every line but the taxes
+func (o *Offer) pretax() float64 {
+	return o.sum(LineTax)
+}
+
+// undiscounted is the price before discounts and taxes
+func (o *Offer) undiscounted() float64 {
+	return o.sum(LineDiscount, LineTax)
+}
+
+// sum adds up the lines of the breakdown but the given kinds
+func (o *Offer) sum(except ...LineKind) (total float64) {
+	for _, item := range o.Breakdown {
+		skip := false
+		for _, kind := range except {
+			skip = skip || item.Kind == kind
+		}
+		if !skip {
+			total += item.Subtotal
+		}
+	}
+	return
+}
+
+// reprice computes the taxes over every other line and
+// sets the total to the sum of the breakdown
+func (o *Offer) reprice() {
+	lines := make([]LineItem, 0, len(o.Breakdown)+1)
+	for _, item := range o.Breakdown {
+		if item.Kind != LineTax {
+			lines = append(lines, item)
+		}
+	}
+	total := o.pretax()
+	if o.TaxRate > 0 && total > 0 {
+		tax := newLineItem(LineTax, fmt.Sprintf("Impostos (%g%%)", o.TaxRate), 1, roundCents(total*o.TaxRate/100))
+		lines = append(lines, tax)
+		total += tax.Subtotal
+	}
+	o.Breakdown = lines
+	o.TotalValue = roundCents(total)
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// testTariff prices vehicle 2 like the original tariff,
+// with a single demand rule per test
+func testTariff() Tariff {
+	t := DefaultTariff()
+	t.Demand = DemandRates{}
+	return t
+}
+
+func day(date string) time.Time {
+	d, err := time.ParseInLocation(DateLayout, date, MovingLocation)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestCalculateTotalValue(t *testing.T) {
+	// a wednesday far from holidays and the end of the month
+	weekday := day("2030-04-10")
+	tests := []struct {
+		name   string
+		tariff func(t *Tariff)
+		move   Move
+		// lines are the subtotals of the breakdown in order
+		lines []float64
+		total float64
+		// line is the description expected in the breakdown
+		line string
+	}{
+		{
+			name:  "labour and distance",
+			move:  Move{Date: weekday},
+			lines: []float64{350, 26},
+			total: 376,
+			line:  "Distância: 10.0 km",
+		},
+		{
+			name:  "extra trips drive back and forth",
+			move:  Move{Date: weekday, Load: Load{Volume: 20}},
+			lines: []float64{350, 78},
+			total: 428,
+			line:  "Distância: 10.0 km, 2 viagens",
+		},
+		{
+			name: "floors and carrying",
+			move: Move{
+				Date: weekday,
+				From: Address{Floor: 3},
+				To:   Address{Floor: 5, Elevator: true, ParkingDistance: 45},
+			},
+			lines: []float64{350, 26, 120, 25, 45},
+			total: 566,
+			line:  "Carregamento de 45 m no destino",
+		},
+		{
+			name:  "basement floors",
+			move:  Move{Date: weekday, From: Address{Floor: -2}},
+			lines: []float64{350, 26, 80},
+			total: 456,
+			line:  "Escada na origem: 2 andares",
+		},
+		{
+			name:  "stops",
+			move:  Move{Date: weekday, Stops: []Address{{Floor: 2}, {}}},
+			lines: []float64{350, 26, 80, 120},
+			total: 576,
+			line:  "Escada na parada 1: 2 andares",
+		},
+		{
+			name:  "disassembly",
+			move:  Move{Date: weekday, Disassembly: 2},
+			lines: []float64{350, 26, 60},
+			total: 436,
+			line:  "Desmontagem e montagem: 2 itens",
+		},
+		{
+			name:   "weekend",
+			tariff: func(t *Tariff) { t.Demand.Weekend = 1.15 },
+			move:   Move{Date: day("2030-04-13")},
+			lines:  []float64{350, 26, 56.4},
+			total:  432.4,
+			line:   "Fim de semana (+15%)",
+		},
+		{
+			name:   "weekday has no weekend rate",
+			tariff: func(t *Tariff) { t.Demand.Weekend = 1.15 },
+			move:   Move{Date: weekday},
+			lines:  []float64{350, 26},
+			total:  376,
+		},
+		{
+			name:   "month end",
+			tariff: func(t *Tariff) { t.Demand.MonthEnd, t.Demand.MonthEndDays = 1.1, 3 },
+			move:   Move{Date: day("2030-04-29")},
+			lines:  []float64{350, 26, 37.6},
+			total:  413.6,
+			line:   "Fim de mês (+10%)",
+		},
+		{
+			name:   "holiday",
+			tariff: func(t *Tariff) { t.Demand.Holiday = 1.25 },
+			move:   Move{Date: day("2030-11-15")},
+			lines:  []float64{350, 26, 94},
+			total:  470,
+			line:   "Feriado: Proclamação da República (+25%)",
+		},
+		{
+			name: "utilisation picks the highest threshold reached",
+			tariff: func(t *Tariff) {
+				t.Demand.Utilisation = []UtilisationRate{{From: 0.5, Multiplier: 1.1}, {From: 0.8, Multiplier: 1.25}}
+			},
+			move:  Move{Date: weekday, Utilisation: map[int]float64{2: 0.85}},
+			lines: []float64{350, 26, 94},
+			total: 470,
+			line:  "Alta procura: 85% dos veículos reservados (+25%)",
+		},
+		{
+			name:   "demand multiplies surcharges and services",
+			tariff: func(t *Tariff) { t.Demand.Weekend = 1.5 },
+			move:   Move{Date: day("2030-04-13"), Disassembly: 1},
+			lines:  []float64{350, 26, 30, 203},
+			total:  609,
+		},
+		{
+			name:   "tax",
+			tariff: func(t *Tariff) { t.TaxRate = 10 },
+			move:   Move{Date: weekday},
+			lines:  []float64{350, 26, 37.6},
+			total:  413.6,
+			line:   "Impostos (10%)",
+		},
+		{
+			name:  "unknown date has no demand",
+			move:  Move{},
+			lines: []float64{350, 26},
+			total: 376,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tariff := testTariff()
+			if tt.tariff != nil {
+				tt.tariff(&tariff)
+			}
+			o := Offer{VehicleType: 2, Distance: 10}
+			if err := o.CheckCapacity(tariff, tt.move.Load); err != nil {
+				t.Fatal(err)
+			}
+			if err := o.CalculateTotalValue(tariff, tt.move); err != nil {
+				t.Fatal(err)
+			}
+			checkBreakdown(t, o, tt.lines, tt.total)
+			if tt.line != "" && !hasLine(o, tt.line) {
+				t.Errorf("no line %q in %+v", tt.line, o.Breakdown)
+			}
+		})
+	}
+}
+
+func TestCalculateTotalValueUnknownVehicle(t *testing.T) {
+	o := Offer{VehicleType: 9, Distance: 10}
+	if err := o.CalculateTotalValue(testTariff(), Move{}); err != ErrVehicleNotFound {
+		t.Errorf("err = %v, want %v", err, ErrVehicleNotFound)
+	}
+}
+
+func checkBreakdown(t *testing.T, o Offer, lines []float64, total float64) {
+	t.Helper()
+	if len(o.Breakdown) != len(lines) {
+		t.Fatalf("breakdown %+v, want subtotals %v", o.Breakdown, lines)
+	}
+	for i, item := range o.Breakdown {
+		if item.Subtotal != lines[i] {
+			t.Errorf("line %d %q = %v, want %v", i, item.Description, item.Subtotal, lines[i])
+		}
+	}
+	if o.TotalValue != total {
+		t.Errorf("total = %v, want %v", o.TotalValue, total)
+	}
+}
+
+func hasLine(o Offer, description string) bool {
+	for _, item := range o.Breakdown {
+		if item.Description == description {
+			return true
+		}
+	}
+	return false
+}
@@ -1,80 +1,147 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
+)
 
-	mgo "gopkg.in/mgo.v2"
+var (
+	ErrMovingDateRequired = errors.New("Moving date is required")
+	ErrMovingDateInPast   = errors.New("Moving date must not be in the past")
 )
 
 type Profile struct {
-	ID             int       `bson:"_id" json:"-"`
+	ID             int       `bson:"_id" json:"numero"`
+	Phone          string    `bson:"phone" json:"telefone"`
 	Inventory      []Room    `bson:"inventory" json:"inventario"`
 	CurrentAddress Address   `bson:"current_address" json:"endereco_atual"`
 	NewAddress     Address   `bson:"new_address" json:"endereco_novo"`
+	Stops          []Stop    `bson:"stops,omitempty" json:"paradas,omitempty"`
 	MovingData     time.Time `bson:"moving_data" json:"data_mudanca"`
 	MovingTime     time.Time `bson:"moving_time" json:"horario_mudanca"`
+	Booking        *Booking  `bson:"booking,omitempty" json:"agendamento,omitempty"`
 	Offer          Offer     `bson:"offer" json:"oferta"`
+	PreviousOffers []Offer   `bson:"previous_offers,omitempty" json:"ofertas_anteriores,omitempty"`
 }
 
 type Room struct {
 	Name  string `bson:"name" json:"nome"`
 	Boxes []Box  `bson:"boxes" json:"caixas"`
+	// Stop is where the room is picked up or dropped off,
+	// the current and new addresses when empty
+	Stop string `bson:"stop,omitempty" json:"parada,omitempty"`
 }
 
 type Box struct {
-	Items []Item `bson:"items" json:"items"`
+	ID     string `bson:"id" json:"id"`
+	Number int    `bson:"number" json:"numero"`
+	Label  string `bson:"label" json:"etiqueta"`
+	Items  []Item `bson:"items" json:"items"`
+	// Stop overrides the stop of the room for this box
+	Stop string `bson:"stop,omitempty" json:"parada,omitempty"`
 }
 
 type Item struct {
-	Quantity int    `bson:"quantity" json:"quantidade"`
-	Type     string `bson:"type" json:"tipo"`
+	Quantity  int    `bson:"quantity" json:"quantidade"`
+	Type      string `bson:"type" json:"tipo"`
+	CatalogID string `bson:"catalog_id,omitempty" json:"catalogo,omitempty"`
 }
 
 type Address struct {
-	Street    string  `bson:"street" json:"rua"`
-	Number    int     `bson:"number" json:"numero"`
-	Latitude  float64 `bson:"latitude" json:"latitude"`
-	Longitude float64 `bson:"longitude" json:"longitude"`
-}
-
-type Offer struct {
-	VehicleType int     `bson:"vehicle" json:"-"`
-	KmValue     float64 `bson:"km_value" json:"valor_por_km"`
-	Distance    float64 `bson:"distance" json:"distancia"`
-	LabourValue float64 `bson:"labour_value" json:"mao_de_obra"`
-	TotalValue  float64 `bson:"total_value" json:"total_value"`
-}
-
-func (p *Profile) CreateOrUpdate(db *mgo.Database) (err error) {
-	_, err = db.C("profiles").UpsertId(p.ID, p)
-	return
+	Street        string  `bson:"street" json:"rua"`
+	Number        int     `bson:"number" json:"numero"`
+	Complement    string  `bson:"complement,omitempty" json:"complemento,omitempty"`
+	Neighbourhood string  `bson:"neighbourhood,omitempty" json:"bairro,omitempty"`
+	City          string  `bson:"city,omitempty" json:"cidade,omitempty"`
+	State         string  `bson:"state,omitempty" json:"uf,omitempty"`
+	CEP           string  `bson:"cep,omitempty" json:"cep,omitempty"`
+	Latitude      float64 `bson:"latitude" json:"latitude"`
+	Longitude     float64 `bson:"longitude" json:"longitude"`
+	// Floor is 0 for the ground floor, negative below it
+	Floor    int  `bson:"floor" json:"andar"`
+	Elevator bool `bson:"elevator" json:"elevador"`
+	// ParkingDistance is how many metres things are
+	// carried between the door and the vehicle
+	ParkingDistance float64 `bson:"parking_distance" json:"distancia_estacionamento"`
 }
 
-func (p *Profile) DeleteByID(db *mgo.Database) error {
-	return db.C("profiles").RemoveId(p.ID)
+// Normalize trims the fields, writes the CEP as 00000-000
+// and the state in upper case
+func (a *Address) Normalize() {
+	a.Street = strings.TrimSpace(a.Street)
+	a.Complement = strings.TrimSpace(a.Complement)
+	a.Neighbourhood = strings.TrimSpace(a.Neighbourhood)
+	a.City = strings.TrimSpace(a.City)
+	a.State = strings.ToUpper(strings.TrimSpace(a.State))
+	if digits := CEPDigits(a.CEP); len(digits) == 8 {
+		a.CEP = digits[:5] + "-" + digits[5:]
+	} else {
+		a.CEP = strings.TrimSpace(a.CEP)
+	}
 }
 
-func GetByID(db *mgo.Database, id int) (p Profile, err error) {
-	return p, db.C("profiles").FindId(id).One(&p)
+// CEPDigits strips the punctuation of a CEP
+func CEPDigits(cep string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, cep)
 }
 
-func (o *Offer) CalculateTotalValue() {
-	switch o.VehicleType {
-	case 1:
-		o.TotalValue = 250 + 2.0*o.Distance
-		o.LabourValue = 250
-		o.KmValue = 2.0
-	case 2:
-		o.TotalValue = 350 + 2.6*o.Distance
-		o.LabourValue = 350
-		o.KmValue = 2.6
-	case 3:
-		o.TotalValue = 500 + 3.0*o.Distance
-		o.LabourValue = 500
-		o.KmValue = 3.0
+// Validate checks the address can be located, naming which
+// one of the profile addresses is wrong in the error
+func (a *Address) Validate(name string) error {
+	a.Normalize()
+	if a.Street == "" {
+		return fmt.Errorf("%s: street is required", name)
+	}
+	if a.Number < 0 {
+		return fmt.Errorf("%s: number must not be negative", name)
+	}
+	if a.Floor < -10 || a.Floor > 200 {
+		return fmt.Errorf("%s: floor out of range", name)
+	}
+	if a.ParkingDistance < 0 {
+		return fmt.Errorf("%s: parking distance must not be negative", name)
 	}
+	if a.CEP != "" && len(CEPDigits(a.CEP)) != 8 {
+		return fmt.Errorf("%s: CEP must have 8 digits", name)
+	}
+	if a.State != "" && len(a.State) != 2 {
+		return fmt.Errorf("%s: state must be the 2 letter abbreviation", name)
+	}
+	if a.Latitude < -90 || a.Latitude > 90 || a.Longitude < -180 || a.Longitude > 180 {
+		return fmt.Errorf("%s: coordinates out of range", name)
+	}
+	if a.Latitude == 0 && a.Longitude == 0 {
+		return fmt.Errorf("%s: coordinates are required", name)
+	}
+	return nil
 }
 
-func Create(db *mgo.Database, p Profile) (err error) {
-	return db.C("profiles").Insert(&p)
+// ValidateMove checks the addresses and schedule given on
+// registration. A missing moving time falls back to the date.
+// Days are counted in MovingLocation, whatever the server zone
+func (p *Profile) ValidateMove(now time.Time) error {
+	if err := p.CurrentAddress.Validate("endereco_atual"); err != nil {
+		return err
+	}
+	if err := p.NewAddress.Validate("endereco_novo"); err != nil {
+		return err
+	}
+	if p.MovingData.IsZero() {
+		return ErrMovingDateRequired
+	}
+	y, m, d := now.In(MovingLocation).Date()
+	if p.MovingData.Before(time.Date(y, m, d, 0, 0, 0, 0, MovingLocation)) {
+		return ErrMovingDateInPast
+	}
+	if p.MovingTime.IsZero() {
+		p.MovingTime = p.MovingData
+	}
+	return nil
 }
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryRepository keeps profiles in a map guarded by a mutex.
+// It is meant for running and testing the API without MongoDB,
+// so nothing survives a restart
+type MemoryRepository struct {
+	mu       sync.RWMutex
+	profiles map[int]Profile
+	lastID   int
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{profiles: make(map[int]Profile)}
+}
+
+func (r *MemoryRepository) GetByID(id int) (Profile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.profiles[id]
+	if !ok {
+		return Profile{}, ErrProfileNotFound
+	}
+	return p.Clone(), nil
+}
+
+func (r *MemoryRepository) FindBox(boxID string) (Profile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.profiles {
+		if _, _, err := p.BoxByID(boxID); err == nil && boxID != "" {
+			return p.Clone(), nil
+		}
+	}
+	return Profile{}, ErrBoxNotFound
+}
+
+func (r *MemoryRepository) FindByPhone(phone string) (Profile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.profiles {
+		if phone != "" && p.Phone == phone {
+			return p.Clone(), nil
+		}
+	}
+	return Profile{}, ErrProfileNotFound
+}
+
+func (r *MemoryRepository) Create(p *Profile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.profiles {
+		if p.Phone != "" && other.Phone == p.Phone {
+			return ErrPhoneTaken
+		}
+	}
+	for p.ID == 0 {
+		r.lastID++
+		if _, ok := r.profiles[r.lastID]; !ok {
+			p.ID = r.lastID
+		}
+	}
+	if _, ok := r.profiles[p.ID]; ok {
+		return ErrProfileExists
+	}
+	r.profiles[p.ID] = p.Clone()
+	return nil
+}
+
+func (r *MemoryRepository) SetPhone(id int, phone string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.profiles[id]
+	if !ok {
+		return ErrProfileNotFound
+	}
+	if p.Phone != "" && p.Phone != phone {
+		return ErrProfileOwned
+	}
+	for _, other := range r.profiles {
+		if other.ID != id && other.Phone == phone {
+			return ErrPhoneTaken
+		}
+	}
+	p.Phone = phone
+	r.profiles[id] = p
+	return nil
+}
+
+func (r *MemoryRepository) SaveOffer(p *Profile, previous Offer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.profiles[p.ID]
+	if !ok {
+		return ErrProfileNotFound
+	}
+	if stored.Offer.ID != previous.ID || stored.Offer.CurrentStatus() != previous.CurrentStatus() ||
+		stored.Offer.Revision != previous.Revision {
+		return ErrOfferChanged
+	}
+	if stored.assignedOutside(p.Stops) {
+		return ErrStopInUse
+	}
+	p.Offer.Revision = previous.Revision + 1
+	saved := p.Clone()
+	stored.Offer = saved.Offer
+	stored.PreviousOffers = saved.PreviousOffers
+	stored.Booking = saved.Booking
+	stored.MovingData = saved.MovingData
+	stored.MovingTime = saved.MovingTime
+	stored.Stops = saved.Stops
+	r.profiles[p.ID] = stored
+	return nil
+}
+
+func (r *MemoryRepository) DeleteByID(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.profiles[id]; !ok {
+		return ErrProfileNotFound
+	}
+	delete(r.profiles, id)
+	return nil
+}
+
+func (r *MemoryRepository) AddRoom(id int, room Room) error {
+	return r.update(id, func(p *Profile) error {
+		return p.AddRoom(room)
+	})
+}
+
+func (r *MemoryRepository) RenameRoom(id int, oldName, newName string) error {
+	return r.update(id, func(p *Profile) error {
+		return p.RenameRoom(oldName, newName)
+	})
+}
+
+func (r *MemoryRepository) RemoveRoom(id int, name string) error {
+	return r.update(id, func(p *Profile) error {
+		return p.RemoveRoom(name)
+	})
+}
+
+func (r *MemoryRepository) AddBox(id int, roomName, label string, items []Item) (box Box, err error) {
+	err = r.update(id, func(p *Profile) (err error) {
+		box, err = p.AddBox(roomName, label, items)
+		return
+	})
+	return
+}
+
+func (r *MemoryRepository) RemoveBox(id int, roomName, boxID string) error {
+	return r.update(id, func(p *Profile) error {
+		return p.RemoveBox(roomName, boxID)
+	})
+}
+
+func (r *MemoryRepository) AddItem(id int, roomName, boxID string, item Item) error {
+	return r.update(id, func(p *Profile) error {
+		return p.AddItem(roomName, boxID, item)
+	})
+}
+
+func (r *MemoryRepository) SetItemQuantity(id int, roomName, boxID, itemType string, quantity int) error {
+	return r.update(id, func(p *Profile) error {
+		return p.SetItemQuantity(roomName, boxID, itemType, quantity)
+	})
+}
+
+func (r *MemoryRepository) RemoveItem(id int, roomName, boxID, itemType string) error {
+	return r.update(id, func(p *Profile) error {
+		return p.RemoveItem(roomName, boxID, itemType)
+	})
+}
+
+func (r *MemoryRepository) SetRoomStop(id int, roomName, stopID string) error {
+	return r.update(id, func(p *Profile) error {
+		return p.SetRoomStop(roomName, stopID)
+	})
+}
+
+func (r *MemoryRepository) SetBoxStop(id int, roomName, boxID, stopID string) error {
+	return r.update(id, func(p *Profile) error {
+		return p.SetBoxStop(roomName, boxID, stopID)
+	})
+}
+
+// update applies fn to a copy of the profile and stores
+// the copy only when fn succeeds, all under the write lock.
+// The inventory of an accepted offer is not changed. Like in
+// MongoDB, a change bumps the offer revision and expires an
+// open quote
+func (r *MemoryRepository) update(id int, fn func(p *Profile) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.profiles[id]
+	if !ok {
+		return ErrProfileNotFound
+	}
+	if p.Offer.Locked() {
+		return ErrInventoryLocked
+	}
+	p = p.Clone()
+	if err := fn(&p); err != nil {
+		return err
+	}
+	p.Offer.Expire(time.Now())
+	p.Offer.Revision++
+	r.profiles[p.ID] = p
+	return nil
+}
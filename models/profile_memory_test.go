@@ -0,0 +1,144 @@
+package models
+
+import "testing"
+
+func TestMemoryCreate(t *testing.T) {
+	r := NewMemoryRepository()
+	chosen := Profile{ID: 2}
+	if err := r.Create(&chosen); err != nil {
+		t.Fatal(err)
+	}
+	// ids skip the one taken, and profiles without phone do not clash
+	var ids []int
+	for i := 0; i < 2; i++ {
+		p := Profile{}
+		if err := r.Create(&p); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, p.ID)
+	}
+	if ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("ids %v, want [1 3]", ids)
+	}
+	if err := r.Create(&Profile{ID: 2}); err != ErrProfileExists {
+		t.Errorf("err = %v, want %v", err, ErrProfileExists)
+	}
+	owned := Profile{Phone: "+5521999999999"}
+	if err := r.Create(&owned); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Create(&Profile{Phone: "+5521999999999"}); err != ErrPhoneTaken {
+		t.Errorf("err = %v, want %v", err, ErrPhoneTaken)
+	}
+	if p, err := r.FindByPhone("+5521999999999"); err != nil || p.ID != owned.ID {
+		t.Errorf("found profile %d, %v, want %d", p.ID, err, owned.ID)
+	}
+	if _, err := r.FindByPhone(""); err != ErrProfileNotFound {
+		t.Errorf("err = %v finding an empty phone", err)
+	}
+}
+
+func TestMemorySetPhone(t *testing.T) {
+	r := NewMemoryRepository()
+	owned, orphan := Profile{Phone: "+5521999999999"}, Profile{}
+	for _, p := range []*Profile{&owned, &orphan} {
+		if err := r.Create(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name  string
+		id    int
+		phone string
+		err   error
+	}{
+		{name: "phone of another profile", id: orphan.ID, phone: owned.Phone, err: ErrPhoneTaken},
+		{name: "orphan", id: orphan.ID, phone: "+5521988888888"},
+		{name: "same phone again", id: orphan.ID, phone: "+5521988888888"},
+		{name: "another phone", id: orphan.ID, phone: "+5521977777777", err: ErrProfileOwned},
+		{name: "missing profile", id: 99, phone: "+5521977777777", err: ErrProfileNotFound},
+	}
+	for _, tt := range tests {
+		if err := r.SetPhone(tt.id, tt.phone); err != tt.err {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if p, _ := r.GetByID(orphan.ID); p.Phone != "+5521988888888" {
+		t.Errorf("phone %q", p.Phone)
+	}
+}
+
+func TestMemoryInventory(t *testing.T) {
+	r := NewMemoryRepository()
+	p := Profile{}
+	if err := r.Create(&p); err != nil {
+		t.Fatal(err)
+	}
+	check := func(step string, err, want error) {
+		t.Helper()
+		if err != want {
+			t.Fatalf("%s: err = %v, want %v", step, err, want)
+		}
+	}
+	check("add room", r.AddRoom(p.ID, Room{Name: "sala"}), nil)
+	check("add room again", r.AddRoom(p.ID, Room{Name: "sala"}), ErrRoomExists)
+	check("add room to a missing profile", r.AddRoom(99, Room{Name: "sala"}), ErrProfileNotFound)
+	box, err := r.AddBox(p.ID, "sala", "", nil)
+	check("add box", err, nil)
+	_, err = r.AddBox(p.ID, "cozinha", "", nil)
+	check("add box to a missing room", err, ErrRoomNotFound)
+	check("add item", r.AddItem(p.ID, "sala", box.ID, Item{Type: "tv", Quantity: 1}), nil)
+	check("add the same item", r.AddItem(p.ID, "sala", box.ID, Item{Type: "tv", Quantity: 2}), nil)
+	check("add item by box number", r.AddItem(p.ID, "sala", "1", Item{Type: "sofa", Quantity: 1}), nil)
+	check("set quantity", r.SetItemQuantity(p.ID, "sala", box.ID, "sofa", 4), nil)
+	check("set quantity of a missing item", r.SetItemQuantity(p.ID, "sala", box.ID, "mesa", 4), ErrItemNotFound)
+	check("rename room", r.RenameRoom(p.ID, "sala", "estar"), nil)
+
+	found, err := r.FindBox(box.ID)
+	check("find box", err, nil)
+	items := found.Inventory[0].Boxes[0].Items
+	if found.ID != p.ID || found.Inventory[0].Name != "estar" || len(items) != 2 || items[0].Quantity != 3 || items[1].Quantity != 4 {
+		t.Fatalf("inventory %+v", found.Inventory)
+	}
+	// profiles read are copies
+	found.Inventory[0].Boxes[0].Items[0].Quantity = 100
+	if stored, _ := r.GetByID(p.ID); stored.Inventory[0].Boxes[0].Items[0].Quantity != 3 {
+		t.Error("changing a profile read changed the stored one")
+	}
+
+	check("remove item", r.RemoveItem(p.ID, "estar", box.ID, "tv"), nil)
+	check("remove item again", r.RemoveItem(p.ID, "estar", box.ID, "tv"), ErrItemNotFound)
+	check("remove box", r.RemoveBox(p.ID, "estar", box.ID), nil)
+	_, err = r.FindBox(box.ID)
+	check("find removed box", err, ErrBoxNotFound)
+	check("remove room", r.RemoveRoom(p.ID, "estar"), nil)
+	check("remove room again", r.RemoveRoom(p.ID, "estar"), ErrRoomNotFound)
+	check("delete", r.DeleteByID(p.ID), nil)
+	_, err = r.GetByID(p.ID)
+	check("get deleted", err, ErrProfileNotFound)
+}
+
+func TestMemorySaveOfferKeepsAssignedStops(t *testing.T) {
+	r := NewMemoryRepository()
+	p := Profile{
+		Inventory: []Room{{Name: "sala", Boxes: []Box{}}},
+		Stops:     []Stop{{ID: "deposito", Kind: StopPickup}},
+	}
+	if err := r.Create(&p); err != nil {
+		t.Fatal(err)
+	}
+	stale, _ := r.GetByID(p.ID)
+	// the room is assigned to the stop after the stops were read
+	if err := r.SetRoomStop(p.ID, "sala", "deposito"); err != nil {
+		t.Fatal(err)
+	}
+	if err := stale.SetStops(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SaveOffer(&stale, stale.Offer); err != ErrOfferChanged {
+		t.Fatalf("err = %v removing an assigned stop, want %v", err, ErrOfferChanged)
+	}
+	if stored, _ := r.GetByID(p.ID); len(stored.Stops) != 1 {
+		t.Errorf("stops %+v", stored.Stops)
+	}
+}
@@ -0,0 +1,477 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MongoRepository stores profiles in the profiles collection,
+// changing the inventory with atomic updates
+type MongoRepository struct {
+	DB *mgo.Database
+}
+
+func NewMongoRepository(db *mgo.Database) *MongoRepository {
+	return &MongoRepository{DB: db}
+}
+
+func (r *MongoRepository) profiles() *mgo.Collection {
+	return r.DB.C("profiles")
+}
+
+func (r *MongoRepository) GetByID(id int) (p Profile, err error) {
+	err = r.profiles().FindId(id).One(&p)
+	if err == mgo.ErrNotFound {
+		err = ErrProfileNotFound
+	}
+	return
+}
+
+func (r *MongoRepository) FindBox(boxID string) (p Profile, err error) {
+	if boxID == "" {
+		return p, ErrBoxNotFound
+	}
+	err = r.profiles().Find(bson.M{"inventory.boxes.id": boxID}).One(&p)
+	if err == mgo.ErrNotFound {
+		err = ErrBoxNotFound
+	}
+	return
+}
+
+// phoneIndex keeps phone numbers unique among the profiles that
+// have one. Profiles made before authentication store an empty
+// phone, so the index only covers non-empty ones
+const phoneIndex = "phone_unique"
+
+// EnsureIndexes creates the indexes the queries rely on
+func (r *MongoRepository) EnsureIndexes() error {
+	if err := r.profiles().EnsureIndex(mgo.Index{Key: []string{"inventory.boxes.id"}, Sparse: true}); err != nil {
+		return err
+	}
+	// mgo has no partial filters, so the index is created by command
+	return r.DB.Run(bson.D{
+		{Name: "createIndexes", Value: r.profiles().Name},
+		{Name: "indexes", Value: []bson.M{{
+			"key":                     bson.M{"phone": 1},
+			"name":                    phoneIndex,
+			"unique":                  true,
+			"partialFilterExpression": bson.M{"phone": bson.M{"$gt": ""}},
+		}}},
+	}, nil)
+}
+
+// phoneTaken tells whether an insert or update
+// failed on the unique index of phone numbers
+func phoneTaken(err error) bool {
+	return mgo.IsDup(err) && strings.Contains(err.Error(), phoneIndex)
+}
+
+func (r *MongoRepository) FindByPhone(phone string) (p Profile, err error) {
+	if phone == "" {
+		return p, ErrProfileNotFound
+	}
+	err = r.profiles().Find(bson.M{"phone": phone}).One(&p)
+	if err == mgo.ErrNotFound {
+		err = ErrProfileNotFound
+	}
+	return
+}
+
+// Create relies on the unique index of phone numbers,
+// so two profiles created at once cannot share one
+func (r *MongoRepository) Create(p *Profile) (err error) {
+	if p.ID != 0 {
+		err = r.profiles().Insert(p)
+		if phoneTaken(err) {
+			err = ErrPhoneTaken
+		} else if mgo.IsDup(err) {
+			err = ErrProfileExists
+		}
+		return
+	}
+	// ids are taken from a counter, skipping the ones
+	// already used by profiles created with a chosen id
+	for {
+		if p.ID, err = r.nextID(); err != nil {
+			return
+		}
+		err = r.profiles().Insert(p)
+		if phoneTaken(err) {
+			return ErrPhoneTaken
+		} else if !mgo.IsDup(err) {
+			return
+		}
+	}
+}
+
+func (r *MongoRepository) nextID() (int, error) {
+	var counter struct {
+		Seq int `bson:"seq"`
+	}
+	_, err := r.DB.C("counters").FindId("profiles").Apply(mgo.Change{
+		Update:    bson.M{"$inc": bson.M{"seq": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &counter)
+	return counter.Seq, err
+}
+
+// SetPhone only sets the phone while the profile has none,
+// the unique index refusing a phone owned by another profile
+func (r *MongoRepository) SetPhone(id int, phone string) error {
+	err := r.profiles().Update(
+		bson.M{"_id": id, "phone": bson.M{"$in": []interface{}{nil, "", phone}}},
+		bson.M{"$set": bson.M{"phone": phone}},
+	)
+	if phoneTaken(err) {
+		return ErrPhoneTaken
+	} else if err != mgo.ErrNotFound {
+		return err
+	}
+	if _, err = r.GetByID(id); err != nil {
+		return err
+	}
+	return ErrProfileOwned
+}
+
+// SaveOffer only sets the fields of the offer, so concurrent
+// inventory changes are kept. Offers saved before revisions
+// existed have none, which the selector also matches. The
+// selector also asserts no room or box is assigned to a stop
+// left out, which may have been assigned since it was read
+func (r *MongoRepository) SaveOffer(p *Profile, previous Offer) error {
+	stops := []interface{}{nil, ""}
+	for _, stop := range p.Stops {
+		stops = append(stops, stop.ID)
+	}
+	outside := bson.M{"$nin": stops}
+	selector := bson.M{
+		"_id":            p.ID,
+		"offer.id":       orMissing(previous.ID, previous.ID == ""),
+		"offer.status":   orMissing(previous.Status, previous.Status == ""),
+		"offer.revision": orMissing(previous.Revision, previous.Revision == 0),
+		"inventory": bson.M{"$not": bson.M{"$elemMatch": bson.M{"$or": []bson.M{
+			{"stop": outside},
+			{"boxes": bson.M{"$elemMatch": bson.M{"stop": outside}}},
+		}}}},
+	}
+	offer := p.Offer
+	offer.Revision = previous.Revision + 1
+	set := bson.M{
+		"offer":           offer,
+		"previous_offers": p.PreviousOffers,
+		"moving_data":     p.MovingData,
+		"moving_time":     p.MovingTime,
+		"stops":           p.Stops,
+	}
+	update := bson.M{"$set": set}
+	if p.Booking != nil {
+		set["booking"] = p.Booking
+	} else {
+		update["$unset"] = bson.M{"booking": ""}
+	}
+	err := r.profiles().Update(selector, update)
+	if err == mgo.ErrNotFound {
+		stored, err := r.GetByID(p.ID)
+		if err != nil {
+			return err
+		}
+		if stored.assignedOutside(p.Stops) {
+			return ErrStopInUse
+		}
+		return ErrOfferChanged
+	} else if err != nil {
+		return err
+	}
+	p.Offer.Revision = offer.Revision
+	return nil
+}
+
+// orMissing matches a value, or also a missing field when
+// the value is the zero one left out by omitempty
+func orMissing(value interface{}, zero bool) interface{} {
+	if zero {
+		return bson.M{"$in": []interface{}{value, nil}}
+	}
+	return value
+}
+
+func (r *MongoRepository) DeleteByID(id int) (err error) {
+	err = r.profiles().RemoveId(id)
+	if err == mgo.ErrNotFound {
+		err = ErrProfileNotFound
+	}
+	return
+}
+
+// AddRoom appends an empty room to the profile inventory
+// failing with ErrRoomExists if the name is already taken
+func (r *MongoRepository) AddRoom(id int, room Room) (err error) {
+	if room.Boxes == nil {
+		room.Boxes = []Box{}
+	}
+	p, err := r.editable(id)
+	if err != nil {
+		return
+	}
+	return r.inventoryUpdate(p,
+		bson.M{"inventory.name": bson.M{"$ne": room.Name}},
+		bson.M{"$push": bson.M{"inventory": room}},
+		ErrRoomExists,
+	)
+}
+
+// RenameRoom changes the name of a room keeping its boxes
+func (r *MongoRepository) RenameRoom(id int, oldName, newName string) (err error) {
+	p, err := r.editable(id)
+	if err != nil {
+		return
+	}
+	i, err := p.RoomIndex(oldName)
+	if err != nil {
+		return
+	}
+	if err = p.RenameRoom(oldName, newName); err != nil {
+		return
+	}
+	return r.guardedUpdate(p,
+		bson.M{roomPath(i, "name"): oldName, "inventory.name": bson.M{"$ne": newName}},
+		bson.M{"$set": bson.M{roomPath(i, "name"): newName}},
+	)
+}
+
+// RemoveRoom deletes a room and every box inside it
+func (r *MongoRepository) RemoveRoom(id int, name string) (err error) {
+	p, err := r.editable(id)
+	if err != nil {
+		return
+	}
+	return r.inventoryUpdate(p,
+		bson.M{"inventory.name": name},
+		bson.M{"$pull": bson.M{"inventory": bson.M{"name": name}}},
+		ErrRoomNotFound,
+	)
+}
+
+// AddBox appends a new box to the room and returns it
+func (r *MongoRepository) AddBox(id int, roomName, label string, items []Item) (box Box, err error) {
+	p, err := r.editable(id)
+	if err != nil {
+		return
+	}
+	i, err := p.RoomIndex(roomName)
+	if err != nil {
+		return
+	}
+	if box, err = p.AddBox(roomName, label, items); err != nil {
+		return
+	}
+	err = r.guardedUpdate(p,
+		bson.M{roomPath(i, "name"): roomName, roomPath(i, "boxes.number"): bson.M{"$ne": box.Number}},
+		bson.M{"$push": bson.M{roomPath(i, "boxes"): box}},
+	)
+	return
+}
+
+// RemoveBox deletes the box from the room
+func (r *MongoRepository) RemoveBox(id int, roomName, boxID string) (err error) {
+	p, i, j, err := r.locateBox(id, roomName, boxID)
+	if err != nil {
+		return
+	}
+	return r.guardedUpdate(p,
+		bson.M{roomPath(i, "name"): roomName},
+		bson.M{"$pull": bson.M{roomPath(i, "boxes"): bson.M{"id": p.Inventory[i].Boxes[j].ID}}},
+	)
+}
+
+// AddItem puts quantity units of an item type in a box,
+// incrementing the quantity when the type is already there
+func (r *MongoRepository) AddItem(id int, roomName, boxID string, item Item) (err error) {
+	p, i, j, err := r.locateBox(id, roomName, boxID)
+	if err != nil {
+		return
+	}
+	box := p.Inventory[i].Boxes[j]
+	items := boxPath(i, j, "items")
+	k, err := box.ItemIndex(item.Type)
+	if err == ErrItemNotFound {
+		return r.guardedUpdate(p,
+			bson.M{boxPath(i, j, "id"): box.ID, items + ".type": bson.M{"$ne": item.Type}},
+			bson.M{"$push": bson.M{items: item}},
+		)
+	}
+	return r.guardedUpdate(p,
+		bson.M{boxPath(i, j, "id"): box.ID, fmt.Sprintf("%s.%d.type", items, k): item.Type},
+		bson.M{"$inc": bson.M{fmt.Sprintf("%s.%d.quantity", items, k): item.Quantity}},
+	)
+}
+
+// SetItemQuantity overrides the quantity of an item already in a box
+func (r *MongoRepository) SetItemQuantity(id int, roomName, boxID, itemType string, quantity int) (err error) {
+	p, i, j, err := r.locateBox(id, roomName, boxID)
+	if err != nil {
+		return
+	}
+	box := p.Inventory[i].Boxes[j]
+	k, err := box.ItemIndex(itemType)
+	if err != nil {
+		return
+	}
+	item := boxPath(i, j, fmt.Sprintf("items.%d", k))
+	return r.guardedUpdate(p,
+		bson.M{boxPath(i, j, "id"): box.ID, item + ".type": itemType},
+		bson.M{"$set": bson.M{item + ".quantity": quantity}},
+	)
+}
+
+// RemoveItem takes an item type out of a box
+func (r *MongoRepository) RemoveItem(id int, roomName, boxID, itemType string) (err error) {
+	p, i, j, err := r.locateBox(id, roomName, boxID)
+	if err != nil {
+		return
+	}
+	box := p.Inventory[i].Boxes[j]
+	if _, err = box.ItemIndex(itemType); err != nil {
+		return
+	}
+	items := boxPath(i, j, "items")
+	return r.guardedUpdate(p,
+		bson.M{boxPath(i, j, "id"): box.ID, items + ".type": itemType},
+		bson.M{"$pull": bson.M{items: bson.M{"type": itemType}}},
+	)
+}
+
+// MigrateBoxIDs gives an ID, number and label to every box stored
+// before boxes had identifiers. Numbers follow the old slice
+// position, so boxes keep the number they were known by. QR labels
+// printed before point at the old box URLs, which signed tokens
+// replaced, and must be printed again
+func (r *MongoRepository) MigrateBoxIDs() (migrated int, err error) {
+	var p Profile
+	iter := r.profiles().Find(bson.M{"inventory.boxes": bson.M{"$elemMatch": bson.M{"id": bson.M{"$in": []interface{}{nil, ""}}}}}).Iter()
+	for iter.Next(&p) {
+		p.AssignBoxIDs()
+		if err = r.profiles().UpdateId(p.ID, bson.M{"$set": bson.M{"inventory": p.Inventory}}); err != nil {
+			iter.Close()
+			return
+		}
+		migrated++
+		p = Profile{}
+	}
+	err = iter.Close()
+	return
+}
+
+func (r *MongoRepository) locateBox(id int, roomName, boxID string) (p Profile, i, j int, err error) {
+	p, err = r.editable(id)
+	if err != nil {
+		return
+	}
+	i, j, err = p.locateBox(roomName, boxID)
+	return
+}
+
+// SetRoomStop assigns a room to a stop, as long
+// as the stop is still part of the move
+func (r *MongoRepository) SetRoomStop(id int, roomName, stopID string) (err error) {
+	p, err := r.editable(id)
+	if err != nil {
+		return
+	}
+	i, err := p.RoomIndex(roomName)
+	if err != nil {
+		return
+	}
+	selector := bson.M{roomPath(i, "name"): roomName}
+	update := bson.M{"$unset": bson.M{roomPath(i, "stop"): ""}}
+	if stopID != "" {
+		if _, err = p.StopIndex(stopID); err != nil {
+			return
+		}
+		selector["stops.id"] = stopID
+		update = bson.M{"$set": bson.M{roomPath(i, "stop"): stopID}}
+	}
+	return r.guardedUpdate(p, selector, update)
+}
+
+// SetBoxStop assigns a box to a stop, as long
+// as the stop is still part of the move
+func (r *MongoRepository) SetBoxStop(id int, roomName, boxID, stopID string) (err error) {
+	p, i, j, err := r.locateBox(id, roomName, boxID)
+	if err != nil {
+		return
+	}
+	selector := bson.M{boxPath(i, j, "id"): p.Inventory[i].Boxes[j].ID}
+	update := bson.M{"$unset": bson.M{boxPath(i, j, "stop"): ""}}
+	if stopID != "" {
+		if _, err = p.StopIndex(stopID); err != nil {
+			return
+		}
+		selector["stops.id"] = stopID
+		update = bson.M{"$set": bson.M{boxPath(i, j, "stop"): stopID}}
+	}
+	return r.guardedUpdate(p, selector, update)
+}
+
+// editable reads the profile to change its inventory,
+// failing with ErrInventoryLocked once the offer is accepted
+func (r *MongoRepository) editable(id int) (p Profile, err error) {
+	if p, err = r.GetByID(id); err == nil && p.Offer.Locked() {
+		err = ErrInventoryLocked
+	}
+	return
+}
+
+// guardedUpdate applies an update whose selector asserts the
+// inventory still looks like it did when it was read, so a
+// concurrent change makes it fail instead of clobbering data
+func (r *MongoRepository) guardedUpdate(p Profile, selector, update bson.M) error {
+	return r.inventoryUpdate(p, selector, update, ErrConflict)
+}
+
+// inventoryUpdate applies an update to the inventory of the profile
+// read as p, as long as its offer was not saved meanwhile. The same
+// update bumps the offer revision, so an offer priced or accepted
+// on the inventory as it was can no longer be saved, and expires an
+// open quote. When the selector matches nothing it fails with
+// ErrInventoryLocked if the offer was accepted, with ErrConflict if
+// it changed, and with failed otherwise
+func (r *MongoRepository) inventoryUpdate(p Profile, selector, update bson.M, failed error) error {
+	selector["_id"] = p.ID
+	selector["offer.status"] = bson.M{"$nin": lockedStatuses}
+	selector["offer.revision"] = orMissing(p.Offer.Revision, p.Offer.Revision == 0)
+	update["$inc"] = bson.M{"offer.revision": 1}
+	if now := time.Now(); p.Offer.CurrentStatus() == OfferQuoted && !p.Offer.Expired(now) {
+		set, ok := update["$set"].(bson.M)
+		if !ok {
+			set = bson.M{}
+			update["$set"] = set
+		}
+		set["offer.valid_until"] = now
+	}
+	err := r.profiles().Update(selector, update)
+	if err != mgo.ErrNotFound {
+		return err
+	}
+	stored, err := r.editable(p.ID)
+	if err != nil {
+		return err
+	}
+	if stored.Offer.Revision != p.Offer.Revision {
+		return ErrConflict
+	}
+	return failed
+}
+
+func boxPath(i, j int, field string) string {
+	return roomPath(i, fmt.Sprintf("boxes.%d.%s", j, field))
+}
+
+func roomPath(i int, field string) string {
+	return fmt.Sprintf("inventory.%d.%s", i, field)
+}
@@ -0,0 +1,57 @@
+package models
+
+import "errors"
+
+var (
+	ErrProfileNotFound = errors.New("Profile not found")
+	ErrProfileExists   = errors.New("Profile already exists")
+	ErrPhoneTaken      = errors.New("Phone number already has a profile")
+	ErrProfileOwned    = errors.New("Profile already belongs to another phone number")
+)
+
+// ProfileRepository persists profiles and their inventory.
+// Every method returns ErrProfileNotFound when there is no
+// profile with the given id
+type ProfileRepository interface {
+	GetByID(id int) (Profile, error)
+	// FindBox returns the profile owning the box
+	FindBox(boxID string) (Profile, error)
+	// FindByPhone returns the profile owned by the phone number
+	FindByPhone(phone string) (Profile, error)
+	// Create inserts a new profile, assigning it the next
+	// free id when p.ID is zero. A phone number owns at most
+	// one profile, so it fails with ErrPhoneTaken when
+	// another profile has p.Phone
+	Create(p *Profile) error
+	// SetPhone binds a profile without phone number to one. It
+	// fails with ErrPhoneTaken when another profile has the phone
+	// and with ErrProfileOwned when the profile has another one
+	SetPhone(id int, phone string) error
+	// SaveOffer stores the offer of the profile with the offers
+	// it replaced, the booking, the moving date and time the
+	// booking sets and the stops, as long as the stored offer
+	// is still the previous one read with it.
+	// Otherwise nothing is saved and it fails with ErrOfferChanged.
+	// It fails with ErrStopInUse when a room or a box is assigned
+	// to a stop the profile no longer has
+	SaveOffer(p *Profile, previous Offer) error
+	DeleteByID(id int) error
+
+	// The inventory methods fail with ErrInventoryLocked while
+	// the offer is accepted, paid or completed. Along with the
+	// change they expire an open quote and bump the offer
+	// revision, so an offer priced on the inventory as it was
+	// fails to save with ErrOfferChanged
+	AddRoom(id int, room Room) error
+	RenameRoom(id int, oldName, newName string) error
+	RemoveRoom(id int, name string) error
+	AddBox(id int, roomName, label string, items []Item) (Box, error)
+	RemoveBox(id int, roomName, boxID string) error
+	AddItem(id int, roomName, boxID string, item Item) error
+	SetItemQuantity(id int, roomName, boxID, itemType string, quantity int) error
+	RemoveItem(id int, roomName, boxID, itemType string) error
+	// SetRoomStop and SetBoxStop assign a room or a box to one
+	// of the stops of the move, failing with ErrStopNotFound
+	SetRoomStop(id int, roomName, stopID string) error
+	SetBoxStop(id int, roomName, boxID, stopID string) error
+}
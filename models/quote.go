@@ -0,0 +1,49 @@
+package models
+
+// Load is the total volume (m³) and weight (kg) to be moved
+type Load struct {
+	Volume float64 `bson:"volume" json:"volume"`
+	Weight float64 `bson:"weight" json:"peso"`
+}
+
+// StandardBox is the load assumed for a box whose
+// items are not in the catalog
+var StandardBox = Load{Volume: 0.1, Weight: 20}
+
+// Quote prices every vehicle of a tariff side by side
+type Quote struct {
+	Load        Load    `json:"carga"`
+	Offers      []Offer `json:"ofertas"`
+	Recommended int     `json:"veiculo_recomendado"`
+}
+
+// NewQuote prices the move with every vehicle of the tariff,
+// trips, surcharges and services included, and recommends
+// the cheapest one that carries the load in one trip,
+// or the biggest one when none does
+func NewQuote(t Tariff, distance float64, m Move) (q Quote, err error) {
+	q.Load = m.Load
+	var biggest VehicleRate
+	cheapest := -1
+	for i, rate := range t.Vehicles {
+		o := Offer{VehicleType: rate.VehicleType, Distance: distance}
+		if err = o.CheckCapacity(t, m.Load); err != nil {
+			return
+		}
+		if err = o.CalculateTotalValue(t, m); err != nil {
+			return
+		}
+		q.Offers = append(q.Offers, o)
+		if i == 0 || smaller(biggest, rate) {
+			biggest = rate
+		}
+		if o.Trips == 1 && (cheapest < 0 || o.TotalValue < q.Offers[cheapest].TotalValue) {
+			cheapest = len(q.Offers) - 1
+		}
+	}
+	q.Recommended = biggest.VehicleType
+	if cheapest >= 0 {
+		q.Recommended = q.Offers[cheapest].VehicleType
+	}
+	return
+}
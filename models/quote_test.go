@@ -0,0 +1,59 @@
+package models
+
+import "testing"
+
+func TestNewQuote(t *testing.T) {
+	tests := []struct {
+		name        string
+		load        Load
+		trips       []int
+		recommended int
+	}{
+		{name: "small load", load: Load{Volume: 2, Weight: 300}, trips: []int{1, 1, 1}, recommended: 1},
+		{name: "too big for the van", load: Load{Volume: 5, Weight: 1000}, trips: []int{2, 1, 1}, recommended: 2},
+		{name: "too heavy for the truck", load: Load{Volume: 10, Weight: 2000}, trips: []int{4, 2, 1}, recommended: 3},
+		{name: "too big for every vehicle", load: Load{Volume: 40, Weight: 6000}, trips: []int{14, 4, 2}, recommended: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := NewQuote(testTariff(), 10, Move{Date: day("2030-04-10"), Load: tt.load})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(q.Offers) != len(tt.trips) {
+				t.Fatalf("%d offers, want %d", len(q.Offers), len(tt.trips))
+			}
+			for i, o := range q.Offers {
+				if o.VehicleType != i+1 || o.Trips != tt.trips[i] || o.Distance != 10 || o.TotalValue <= 0 {
+					t.Errorf("offer %d: vehicle %d, %d trips, %v km, total %v", i, o.VehicleType, o.Trips, o.Distance, o.TotalValue)
+				}
+			}
+			if q.Recommended != tt.recommended || q.Load != tt.load {
+				t.Errorf("recommended %d for %+v, want %d", q.Recommended, q.Load, tt.recommended)
+			}
+		})
+	}
+}
+
+func TestNewQuoteOrdersByCapacity(t *testing.T) {
+	// listed from the biggest vehicle, the one without limits first
+	tariff := testTariff()
+	tariff.Vehicles = []VehicleRate{
+		{VehicleType: 4, LabourValue: 900, KmValue: 5},
+		tariff.Vehicles[2], tariff.Vehicles[1], tariff.Vehicles[0],
+	}
+	q, err := NewQuote(tariff, 10, Move{Date: day("2030-04-10"), Load: Load{Volume: 5, Weight: 1000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Recommended != 2 {
+		t.Errorf("recommended %d, want 2", q.Recommended)
+	}
+	q, err = NewQuote(tariff, 10, Move{Date: day("2030-04-10"), Load: Load{Volume: 400}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Recommended != 4 {
+		t.Errorf("recommended %d for a load only vehicle 4 takes, want 4", q.Recommended)
+	}
+}
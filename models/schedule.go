@@ -0,0 +1,158 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"time"
+)
+
+var (
+	ErrSlotNotFound    = errors.New("Slot not found")
+	ErrSlotFull        = errors.New("Slot is fully booked")
+	ErrBookingNotFound = errors.New("Booking not found")
+)
+
+// DateLayout is how move dates are written in the API
+const DateLayout = "2006-01-02"
+
+// MovingLocation is the time zone slots are in
+var MovingLocation = saoPaulo()
+
+func saoPaulo() *time.Location {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		return time.FixedZone("BRT", -3*60*60)
+	}
+	return loc
+}
+
+// Slot is a period of every day when a vehicle type can be
+// booked, Capacity being how many vehicles of the type
+// are available in it
+type Slot struct {
+	ID          string `bson:"_id" json:"id"`
+	VehicleType int    `bson:"vehicle" json:"veiculo"`
+	Start       string `bson:"start" json:"inicio"`
+	End         string `bson:"end" json:"fim"`
+	Capacity    int    `bson:"capacity" json:"capacidade"`
+}
+
+// Booking is the slot reserved for the move of a profile
+type Booking struct {
+	Date   string    `bson:"date" json:"data"`
+	SlotID string    `bson:"slot" json:"horario"`
+	Start  time.Time `bson:"start" json:"inicio"`
+	End    time.Time `bson:"end" json:"fim"`
+}
+
+// SlotRepository stores the daily slots
+type SlotRepository interface {
+	List() ([]Slot, error)
+	Get(id string) (Slot, error)
+	Save(s Slot) error
+	Delete(id string) error
+}
+
+// BookingRepository counts the vehicles booked per day and slot.
+// Book must never let more profiles than the slot capacity in,
+// even when called concurrently, and booking a slot twice
+// for the same profile is not an error
+type BookingRepository interface {
+	Book(date string, slot Slot, profile int) error
+	Release(date, slotID string, profile int) error
+	// Booked returns how many vehicles are booked per slot id
+	Booked(date string) (map[string]int, error)
+}
+
+// Validate checks the id is a slug, the period is
+// within a day and there is capacity
+func (s *Slot) Validate() error {
+	if !slug.MatchString(s.ID) {
+		return errors.New("Slot id must be lowercase letters, digits and dashes")
+	}
+	if s.VehicleType < 1 {
+		return errors.New("Slot vehicle type is required")
+	}
+	start, errS := time.Parse("15:04", s.Start)
+	end, errE := time.Parse("15:04", s.End)
+	if errS != nil || errE != nil {
+		return errors.New("Slot start and end must be written as 15:04")
+	}
+	if !end.After(start) {
+		return errors.New("Slot must end after it starts")
+	}
+	if s.Capacity < 0 {
+		return errors.New("Slot capacity cannot be negative")
+	}
+	return nil
+}
+
+// On returns the booking of the slot on the given date
+func (s *Slot) On(date string) (b Booking, err error) {
+	day, err := time.ParseInLocation(DateLayout, date, MovingLocation)
+	if err != nil {
+		return
+	}
+	start, err := time.Parse("15:04", s.Start)
+	if err != nil {
+		return
+	}
+	end, err := time.Parse("15:04", s.End)
+	if err != nil {
+		return
+	}
+	return Booking{
+		Date:   date,
+		SlotID: s.ID,
+		Start:  day.Add(time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute),
+		End:    day.Add(time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute),
+	}, nil
+}
+
+// Book sets the booking and the moving date and time to its start
+func (p *Profile) Book(b Booking) {
+	p.Booking = &b
+	p.MovingData = b.Start
+	p.MovingTime = b.Start
+}
+
+// DefaultSlots is stored when there are no slots: a morning
+// and an afternoon for each vehicle type of the default tariff
+func DefaultSlots() []Slot {
+	return []Slot{
+		{ID: "1-manha", VehicleType: 1, Start: "08:00", End: "12:00", Capacity: 3},
+		{ID: "1-tarde", VehicleType: 1, Start: "13:00", End: "17:00", Capacity: 3},
+		{ID: "2-manha", VehicleType: 2, Start: "08:00", End: "12:00", Capacity: 2},
+		{ID: "2-tarde", VehicleType: 2, Start: "13:00", End: "17:00", Capacity: 2},
+		{ID: "3-manha", VehicleType: 3, Start: "08:00", End: "12:00", Capacity: 1},
+		{ID: "3-tarde", VehicleType: 3, Start: "13:00", End: "17:00", Capacity: 1},
+	}
+}
+
+// LoadSlots reads a JSON array of slots from a file
+func LoadSlots(path string) (slots []Slot, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &slots)
+	return
+}
+
+// SeedSlots saves the given slots when there are none
+func SeedSlots(r SlotRepository, slots []Slot) error {
+	existing, err := r.List()
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	for _, s := range slots {
+		if err = s.Validate(); err != nil {
+			return err
+		}
+		if err = r.Save(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemorySlotRepository keeps the daily slots in a map
+type MemorySlotRepository struct {
+	mu    sync.RWMutex
+	slots map[string]Slot
+}
+
+func NewMemorySlotRepository() *MemorySlotRepository {
+	return &MemorySlotRepository{slots: make(map[string]Slot)}
+}
+
+func (r *MemorySlotRepository) List() ([]Slot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	slots := make([]Slot, 0, len(r.slots))
+	for _, s := range r.slots {
+		slots = append(slots, s)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].ID < slots[j].ID })
+	return slots, nil
+}
+
+func (r *MemorySlotRepository) Get(id string) (Slot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.slots[id]
+	if !ok {
+		return s, ErrSlotNotFound
+	}
+	return s, nil
+}
+
+func (r *MemorySlotRepository) Save(s Slot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.slots[s.ID] = s
+	return nil
+}
+
+func (r *MemorySlotRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.slots[id]; !ok {
+		return ErrSlotNotFound
+	}
+	delete(r.slots, id)
+	return nil
+}
+
+// MemoryBookingRepository keeps the profiles booked
+// per date and slot in a map
+type MemoryBookingRepository struct {
+	mu       sync.Mutex
+	bookings map[string]map[string][]int
+}
+
+func NewMemoryBookingRepository() *MemoryBookingRepository {
+	return &MemoryBookingRepository{bookings: make(map[string]map[string][]int)}
+}
+
+func (r *MemoryBookingRepository) Book(date string, slot Slot, profile int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	day, ok := r.bookings[date]
+	if !ok {
+		day = make(map[string][]int)
+		r.bookings[date] = day
+	}
+	for _, id := range day[slot.ID] {
+		if id == profile {
+			return nil
+		}
+	}
+	if len(day[slot.ID]) >= slot.Capacity {
+		return ErrSlotFull
+	}
+	day[slot.ID] = append(day[slot.ID], profile)
+	return nil
+}
+
+func (r *MemoryBookingRepository) Release(date, slotID string, profile int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	profiles := r.bookings[date][slotID]
+	for i, id := range profiles {
+		if id == profile {
+			r.bookings[date][slotID] = append(profiles[:i:i], profiles[i+1:]...)
+			return nil
+		}
+	}
+	return ErrBookingNotFound
+}
+
+func (r *MemoryBookingRepository) Booked(date string) (map[string]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	booked := make(map[string]int)
+	for slotID, profiles := range r.bookings[date] {
+		booked[slotID] = len(profiles)
+	}
+	return booked, nil
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MongoSlotRepository stores the daily slots in the slots collection
+type MongoSlotRepository struct {
+	DB *mgo.Database
+}
+
+func NewMongoSlotRepository(db *mgo.Database) *MongoSlotRepository {
+	return &MongoSlotRepository{DB: db}
+}
+
+func (r *MongoSlotRepository) slots() *mgo.Collection {
+	return r.DB.C("slots")
+}
+
+func (r *MongoSlotRepository) List() (slots []Slot, err error) {
+	slots = []Slot{}
+	err = r.slots().Find(nil).Sort("_id").All(&slots)
+	return
+}
+
+func (r *MongoSlotRepository) Get(id string) (s Slot, err error) {
+	err = r.slots().FindId(id).One(&s)
+	if err == mgo.ErrNotFound {
+		err = ErrSlotNotFound
+	}
+	return
+}
+
+func (r *MongoSlotRepository) Save(s Slot) (err error) {
+	_, err = r.slots().UpsertId(s.ID, &s)
+	return
+}
+
+func (r *MongoSlotRepository) Delete(id string) (err error) {
+	err = r.slots().RemoveId(id)
+	if err == mgo.ErrNotFound {
+		err = ErrSlotNotFound
+	}
+	return
+}
+
+// MongoBookingRepository keeps one document per date and slot
+// in the bookings collection, with the booked profiles and
+// their count, so a booking is a single guarded update
+type MongoBookingRepository struct {
+	DB *mgo.Database
+}
+
+func NewMongoBookingRepository(db *mgo.Database) *MongoBookingRepository {
+	return &MongoBookingRepository{DB: db}
+}
+
+func (r *MongoBookingRepository) bookings() *mgo.Collection {
+	return r.DB.C("bookings")
+}
+
+// EnsureIndexes creates the indexes the queries rely on
+func (r *MongoBookingRepository) EnsureIndexes() error {
+	return r.bookings().EnsureIndex(mgo.Index{Key: []string{"date"}})
+}
+
+// Book increments the count only while it is below the capacity.
+// When the slot is full the guard does not match and the upsert
+// fails inserting a second document with the same _id. Two first
+// bookings of a slot both insert it, so the one failing is retried
+func (r *MongoBookingRepository) Book(date string, slot Slot, profile int) error {
+	id := date + "/" + slot.ID
+	if slot.Capacity <= 0 {
+		// the upsert would insert the first booking of a closed
+		// slot, as a missing document matches no selector
+		return r.booked(id, profile)
+	}
+	selector := bson.M{"_id": id, "count": bson.M{"$lt": slot.Capacity}, "profiles": bson.M{"$ne": profile}}
+	update := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$push":        bson.M{"profiles": profile},
+		"$setOnInsert": bson.M{"date": date, "slot": slot.ID},
+	}
+	_, err := r.bookings().Upsert(selector, update)
+	if mgo.IsDup(err) {
+		// the first booking of the slot made at the same time
+		// inserted it, so the update now finds it unless full
+		_, err = r.bookings().Upsert(selector, update)
+	}
+	if mgo.IsDup(err) {
+		return r.booked(id, profile)
+	}
+	return err
+}
+
+// booked tells a slot booked by the profile from a full one
+func (r *MongoBookingRepository) booked(id string, profile int) error {
+	n, err := r.bookings().Find(bson.M{"_id": id, "profiles": profile}).Count()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	return ErrSlotFull
+}
+
+func (r *MongoBookingRepository) Release(date, slotID string, profile int) error {
+	err := r.bookings().Update(
+		bson.M{"_id": date + "/" + slotID, "profiles": profile},
+		bson.M{"$inc": bson.M{"count": -1}, "$pull": bson.M{"profiles": profile}},
+	)
+	if err == mgo.ErrNotFound {
+		err = ErrBookingNotFound
+	}
+	return err
+}
+
+func (r *MongoBookingRepository) Booked(date string) (map[string]int, error) {
+	var docs []struct {
+		Slot  string `bson:"slot"`
+		Count int    `bson:"count"`
+	}
+	if err := r.bookings().Find(bson.M{"date": date}).All(&docs); err != nil {
+		return nil, err
+	}
+	booked := make(map[string]int, len(docs))
+	for _, doc := range docs {
+		booked[doc.Slot] = doc.Count
+	}
+	return booked, nil
+}
@@ -0,0 +1,98 @@
+package models
+
+import "testing"
+
+func TestBookCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		// profiles book the slot in order, err is the
+		// outcome of each booking
+		profiles []int
+		errs     []error
+		booked   int
+	}{
+		{
+			name:     "within capacity",
+			capacity: 2,
+			profiles: []int{1, 2},
+			errs:     []error{nil, nil},
+			booked:   2,
+		},
+		{
+			name:     "full",
+			capacity: 2,
+			profiles: []int{1, 2, 3},
+			errs:     []error{nil, nil, ErrSlotFull},
+			booked:   2,
+		},
+		{
+			name:     "booking again is not an error",
+			capacity: 1,
+			profiles: []int{1, 1, 2},
+			errs:     []error{nil, nil, ErrSlotFull},
+			booked:   1,
+		},
+		{
+			name:     "no capacity",
+			capacity: 0,
+			profiles: []int{1},
+			errs:     []error{ErrSlotFull},
+			booked:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewMemoryBookingRepository()
+			slot := Slot{ID: "manha", VehicleType: 2, Start: "08:00", End: "12:00", Capacity: tt.capacity}
+			for i, profile := range tt.profiles {
+				if err := r.Book("2030-04-10", slot, profile); err != tt.errs[i] {
+					t.Errorf("booking %d by profile %d: err = %v, want %v", i+1, profile, err, tt.errs[i])
+				}
+			}
+			booked, err := r.Booked("2030-04-10")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if booked[slot.ID] != tt.booked {
+				t.Errorf("booked = %d, want %d", booked[slot.ID], tt.booked)
+			}
+		})
+	}
+}
+
+func TestReleaseFreesTheSlot(t *testing.T) {
+	r := NewMemoryBookingRepository()
+	slot := Slot{ID: "manha", VehicleType: 2, Start: "08:00", End: "12:00", Capacity: 1}
+	if err := r.Book("2030-04-10", slot, 1); err != nil {
+		t.Fatal(err)
+	}
+	// other dates have their own capacity
+	if err := r.Book("2030-04-11", slot, 2); err != nil {
+		t.Errorf("err = %v booking another date", err)
+	}
+	if err := r.Release("2030-04-10", slot.ID, 2); err != ErrBookingNotFound {
+		t.Errorf("err = %v releasing a booking never made, want %v", err, ErrBookingNotFound)
+	}
+	if err := r.Release("2030-04-10", slot.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Book("2030-04-10", slot, 2); err != nil {
+		t.Errorf("err = %v after the slot was released", err)
+	}
+}
+
+func TestSlotOn(t *testing.T) {
+	slot := Slot{ID: "tarde", VehicleType: 2, Start: "13:30", End: "18:00", Capacity: 1}
+	b, err := slot.On("2030-04-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.SlotID != slot.ID || b.Start.In(MovingLocation).Format("2006-01-02 15:04") != "2030-04-10 13:30" ||
+		b.End.Sub(b.Start).Hours() != 4.5 {
+		t.Errorf("booking %+v", b)
+	}
+	if _, err := slot.On("10/04/2030"); err == nil {
+		t.Error("no error for a date in another layout")
+	}
+}
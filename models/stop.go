@@ -0,0 +1,141 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	ErrStopNotFound = errors.New("Stop not found")
+	ErrStopInUse    = errors.New("Stop still has rooms or boxes assigned")
+)
+
+// MaxStops limits the stops of a move
+const MaxStops = 10
+
+// StopKind tells what the move does at a stop
+type StopKind string
+
+const (
+	StopPickup  StopKind = "coleta"
+	StopDropOff StopKind = "entrega"
+)
+
+// Stop is an address the move goes by between the current and
+// the new address, to pick up or drop off part of the inventory.
+// Rooms and boxes assigned to a pickup stop are collected there
+// and the ones assigned to a drop-off stop are left there
+type Stop struct {
+	ID      string   `bson:"id" json:"id"`
+	Name    string   `bson:"name" json:"nome"`
+	Kind    StopKind `bson:"kind" json:"tipo"`
+	Address Address  `bson:"address" json:"endereco"`
+}
+
+// StopIndex returns the position of the stop in the route
+func (p *Profile) StopIndex(id string) (int, error) {
+	for i, stop := range p.Stops {
+		if stop.ID == id {
+			return i, nil
+		}
+	}
+	return -1, ErrStopNotFound
+}
+
+// Route lists the addresses of the move in order
+func (p *Profile) Route() []Address {
+	route := []Address{p.CurrentAddress}
+	for _, stop := range p.Stops {
+		route = append(route, stop.Address)
+	}
+	return append(route, p.NewAddress)
+}
+
+// SetStops replaces the stops of the move, in the order given.
+// Stops with an ID keep it, new ones get a fresh one, and stops
+// left out must not have rooms or boxes assigned anymore
+func (p *Profile) SetStops(stops []Stop) error {
+	if len(stops) > MaxStops {
+		return fmt.Errorf("A move can have up to %d stops", MaxStops)
+	}
+	kept := make(map[string]bool)
+	for i := range stops {
+		stop := &stops[i]
+		stop.Name = strings.TrimSpace(stop.Name)
+		if stop.Kind != StopPickup && stop.Kind != StopDropOff {
+			return fmt.Errorf("Stop kind must be %s or %s", StopPickup, StopDropOff)
+		}
+		if err := stop.Address.Validate(fmt.Sprintf("parada %d", i+1)); err != nil {
+			return err
+		}
+		if stop.ID == "" {
+			stop.ID = bson.NewObjectId().Hex()
+		} else if _, err := p.StopIndex(stop.ID); err != nil {
+			return err
+		}
+		if kept[stop.ID] {
+			return fmt.Errorf("Stop %s is listed twice", stop.ID)
+		}
+		kept[stop.ID] = true
+	}
+	if p.assignedOutside(stops) {
+		return ErrStopInUse
+	}
+	p.Stops = stops
+	return nil
+}
+
+// assignedOutside tells whether a room or a box
+// is assigned to a stop that is not one of stops
+func (p *Profile) assignedOutside(stops []Stop) bool {
+	kept := make(map[string]bool)
+	for _, stop := range stops {
+		kept[stop.ID] = true
+	}
+	for _, room := range p.Inventory {
+		if room.Stop != "" && !kept[room.Stop] {
+			return true
+		}
+		for _, box := range room.Boxes {
+			if box.Stop != "" && !kept[box.Stop] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetRoomStop assigns the room to a stop, or back to the
+// current and new addresses when stopID is empty
+func (p *Profile) SetRoomStop(roomName, stopID string) error {
+	i, err := p.RoomIndex(roomName)
+	if err != nil {
+		return err
+	}
+	if stopID != "" {
+		if _, err = p.StopIndex(stopID); err != nil {
+			return err
+		}
+	}
+	p.Inventory[i].Stop = stopID
+	return nil
+}
+
+// SetBoxStop assigns the box to a stop, or to the stop of
+// its room when stopID is empty
+func (p *Profile) SetBoxStop(roomName, boxID, stopID string) error {
+	i, j, err := p.locateBox(roomName, boxID)
+	if err != nil {
+		return err
+	}
+	if stopID != "" {
+		if _, err = p.StopIndex(stopID); err != nil {
+			return err
+		}
+	}
+	p.Inventory[i].Boxes[j].Stop = stopID
+	return nil
+}
@@ -0,0 +1,259 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"time"
+)
+
+var (
+	ErrTariffNotFound  = errors.New("Tariff not found")
+	ErrTariffInUse     = errors.New("Tariff is already in effect and cannot be removed")
+	ErrVehicleNotFound = errors.New("Vehicle type is not priced by the tariff")
+)
+
+// Tariff is an immutable price table. A new version is created
+// for every price change and becomes effective at EffectiveAt
+type Tariff struct {
+	Version     int            `bson:"_id" json:"versao"`
+	EffectiveAt time.Time      `bson:"effective_at" json:"vigencia"`
+	CreatedAt   time.Time      `bson:"created_at" json:"criada_em"`
+	Vehicles    []VehicleRate  `bson:"vehicles" json:"veiculos"`
+	Surcharges  SurchargeRates `bson:"surcharges" json:"acrescimos"`
+	Services    ServiceRates   `bson:"services" json:"servicos"`
+	Demand      DemandRates    `bson:"demand" json:"demanda"`
+	// Cancellation is the policy of the offers priced with the tariff
+	Cancellation CancellationPolicy `bson:"cancellation" json:"cancelamento"`
+	// TaxRate is the percentage of taxes added to the price
+	TaxRate float64 `bson:"tax_rate" json:"imposto_percentual"`
+}
+
+// SurchargeRates price the extra work at each address: floors
+// climbed by stairs or by elevator and, past FreeCarry metres,
+// every 10 metres carried from where the vehicle parks. Every
+// stop between the current and the new address costs PerStop
+type SurchargeRates struct {
+	StairsPerFloor   float64 `bson:"stairs_per_floor" json:"andar_escada"`
+	ElevatorPerFloor float64 `bson:"elevator_per_floor" json:"andar_elevador"`
+	FreeCarry        float64 `bson:"free_carry" json:"carregamento_incluido"`
+	CarryPer10m      float64 `bson:"carry_per_10m" json:"carregamento_10m"`
+	PerStop          float64 `bson:"per_stop" json:"parada_extra"`
+}
+
+// For returns the surcharges of an address, place naming it
+// in the descriptions ("na origem" or "no destino")
+func (r SurchargeRates) For(place string, a Address) (items []LineItem) {
+	floors := a.Floor
+	if floors < 0 {
+		floors = -floors
+	}
+	if floors > 0 {
+		if a.Elevator && r.ElevatorPerFloor > 0 {
+			items = append(items, newLineItem(LineSurcharge, fmt.Sprintf("Elevador %s: %d andares", place, floors), float64(floors), r.ElevatorPerFloor))
+		} else if !a.Elevator && r.StairsPerFloor > 0 {
+			items = append(items, newLineItem(LineSurcharge, fmt.Sprintf("Escada %s: %d andares", place, floors), float64(floors), r.StairsPerFloor))
+		}
+	}
+	if carry := a.ParkingDistance - r.FreeCarry; carry > 0 && r.CarryPer10m > 0 {
+		items = append(items, newLineItem(LineSurcharge, fmt.Sprintf("Carregamento de %.0f m %s", a.ParkingDistance, place), math.Ceil(carry/10), r.CarryPer10m))
+	}
+	return
+}
+
+// Stops returns the charge of the stops of the move
+func (r SurchargeRates) Stops(m Move) (items []LineItem) {
+	if len(m.Stops) > 0 && r.PerStop > 0 {
+		items = append(items, newLineItem(LineSurcharge, fmt.Sprintf("Paradas extras: %d", len(m.Stops)), float64(len(m.Stops)), r.PerStop))
+	}
+	return
+}
+
+// VehicleRate holds the prices and capacity of a vehicle type.
+// A zero MaxVolume or MaxWeight means the limit is unknown
+type VehicleRate struct {
+	VehicleType int     `bson:"vehicle" json:"veiculo"`
+	LabourValue float64 `bson:"labour_value" json:"mao_de_obra"`
+	KmValue     float64 `bson:"km_value" json:"valor_por_km"`
+	MaxVolume   float64 `bson:"max_volume" json:"volume_maximo"`
+	MaxWeight   float64 `bson:"max_weight" json:"peso_maximo"`
+}
+
+// Fits tells if the vehicle carries the load in a single trip
+func (v VehicleRate) Fits(l Load) bool {
+	return v.Trips(l) == 1
+}
+
+// Trips returns how many trips the vehicle needs to carry the load
+func (v VehicleRate) Trips(l Load) int {
+	trips := 1.0
+	if v.MaxVolume > 0 {
+		trips = math.Max(trips, math.Ceil(l.Volume/v.MaxVolume))
+	}
+	if v.MaxWeight > 0 {
+		trips = math.Max(trips, math.Ceil(l.Weight/v.MaxWeight))
+	}
+	return int(trips)
+}
+
+// SmallestFitting returns the vehicle with the least capacity
+// that still carries the load in a single trip
+func (t *Tariff) SmallestFitting(l Load) (best VehicleRate, ok bool) {
+	for _, rate := range t.Vehicles {
+		if !rate.Fits(l) {
+			continue
+		}
+		if !ok || smaller(rate, best) {
+			best, ok = rate, true
+		}
+	}
+	return
+}
+
+// smaller orders vehicles by volume then weight, unknown limits last
+func smaller(a, b VehicleRate) bool {
+	av, bv := capacity(a.MaxVolume), capacity(b.MaxVolume)
+	if av != bv {
+		return av < bv
+	}
+	return capacity(a.MaxWeight) < capacity(b.MaxWeight)
+}
+
+func capacity(limit float64) float64 {
+	if limit == 0 {
+		return math.Inf(1)
+	}
+	return limit
+}
+
+// TariffRepository stores the tariff versions
+type TariffRepository interface {
+	List() ([]Tariff, error)
+	Get(version int) (Tariff, error)
+	// Current returns the tariff in effect at the given time
+	Current(at time.Time) (Tariff, error)
+	// Create stores t under the next version number, returning it
+	Create(t Tariff) (Tariff, error)
+	// Delete removes a tariff that did not take effect yet
+	Delete(version int) error
+}
+
+// DefaultTariff is the tariff stored when none is configured. Its
+// vehicle prices are the ones used before tariffs were configurable,
+// the surcharges, services, demand multipliers and cancellation fees
+// were added with those rules and raise the price of the same move
+func DefaultTariff() Tariff {
+	return Tariff{
+		Vehicles: []VehicleRate{
+			{VehicleType: 1, LabourValue: 250, KmValue: 2.0, MaxVolume: 3, MaxWeight: 650},
+			{VehicleType: 2, LabourValue: 350, KmValue: 2.6, MaxVolume: 12, MaxWeight: 1800},
+			{VehicleType: 3, LabourValue: 500, KmValue: 3.0, MaxVolume: 30, MaxWeight: 5000},
+		},
+		Surcharges: SurchargeRates{StairsPerFloor: 40, ElevatorPerFloor: 5, FreeCarry: 20, CarryPer10m: 15, PerStop: 60},
+		Services:   ServiceRates{DisassemblyPerItem: 30},
+		Demand: DemandRates{
+			Weekend:      1.15,
+			MonthEnd:     1.1,
+			MonthEndDays: 3,
+			Holiday:      1.25,
+			Utilisation:  []UtilisationRate{{From: 0.5, Multiplier: 1.1}, {From: 0.8, Multiplier: 1.25}},
+		},
+		Cancellation: CancellationPolicy{{HoursBefore: 7 * 24, Fee: 0}, {HoursBefore: 48, Fee: 30}},
+	}
+}
+
+// Rate returns the prices of a vehicle type
+func (t *Tariff) Rate(vehicle int) (VehicleRate, error) {
+	for _, rate := range t.Vehicles {
+		if rate.VehicleType == vehicle {
+			return rate, nil
+		}
+	}
+	return VehicleRate{}, ErrVehicleNotFound
+}
+
+// Validate checks the tariff prices every vehicle once with non negative values
+func (t *Tariff) Validate() error {
+	if len(t.Vehicles) == 0 {
+		return errors.New("Tariff must price at least one vehicle")
+	}
+	seen := make(map[int]bool)
+	for _, rate := range t.Vehicles {
+		if rate.VehicleType < 1 {
+			return errors.New("Vehicle type must be greater than zero")
+		}
+		if seen[rate.VehicleType] {
+			return fmt.Errorf("Vehicle type %d is priced twice", rate.VehicleType)
+		}
+		if rate.LabourValue < 0 || rate.KmValue < 0 {
+			return fmt.Errorf("Vehicle type %d has negative prices", rate.VehicleType)
+		}
+		if rate.MaxVolume < 0 || rate.MaxWeight < 0 {
+			return fmt.Errorf("Vehicle type %d has negative capacity", rate.VehicleType)
+		}
+		seen[rate.VehicleType] = true
+	}
+	sc := t.Surcharges
+	if sc.StairsPerFloor < 0 || sc.ElevatorPerFloor < 0 || sc.FreeCarry < 0 || sc.CarryPer10m < 0 || sc.PerStop < 0 {
+		return errors.New("Tariff surcharges cannot be negative")
+	}
+	if t.Services.DisassemblyPerItem < 0 {
+		return errors.New("Tariff services cannot be negative")
+	}
+	if t.TaxRate < 0 || t.TaxRate >= 100 {
+		return errors.New("Tariff tax rate must be a percentage from 0 to 100")
+	}
+	if err := t.Cancellation.Validate(); err != nil {
+		return err
+	}
+	return t.Demand.Validate()
+}
+
+// LoadTariffs reads a JSON array of tariffs from a file
+func LoadTariffs(path string) (tariffs []Tariff, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &tariffs)
+	return
+}
+
+// SeedTariffs creates the given tariffs, ordered by effective
+// date, when the repository has none yet
+func SeedTariffs(r TariffRepository, tariffs []Tariff) error {
+	existing, err := r.List()
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	sort.SliceStable(tariffs, func(i, j int) bool {
+		return tariffs[i].EffectiveAt.Before(tariffs[j].EffectiveAt)
+	})
+	for _, t := range tariffs {
+		if err = t.Validate(); err != nil {
+			return err
+		}
+		if _, err = r.Create(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentTariff picks, among tariffs sorted by version, the one
+// with the latest effective date not after at
+func currentTariff(tariffs []Tariff, at time.Time) (current Tariff, err error) {
+	err = ErrTariffNotFound
+	for _, t := range tariffs {
+		if t.EffectiveAt.After(at) {
+			continue
+		}
+		if err != nil || !t.EffectiveAt.Before(current.EffectiveAt) {
+			current, err = t, nil
+		}
+	}
+	return
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryTariffRepository keeps tariffs in a slice ordered by version
+type MemoryTariffRepository struct {
+	mu      sync.RWMutex
+	tariffs []Tariff
+}
+
+func NewMemoryTariffRepository() *MemoryTariffRepository {
+	return &MemoryTariffRepository{}
+}
+
+func (r *MemoryTariffRepository) List() ([]Tariff, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Tariff{}, r.tariffs...), nil
+}
+
+func (r *MemoryTariffRepository) Get(version int) (Tariff, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.tariffs {
+		if t.Version == version {
+			return t, nil
+		}
+	}
+	return Tariff{}, ErrTariffNotFound
+}
+
+func (r *MemoryTariffRepository) Current(at time.Time) (Tariff, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return currentTariff(r.tariffs, at)
+}
+
+func (r *MemoryTariffRepository) Create(t Tariff) (Tariff, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t.Version = 1
+	if len(r.tariffs) > 0 {
+		t.Version = r.tariffs[len(r.tariffs)-1].Version + 1
+	}
+	t.CreatedAt = time.Now()
+	t.Vehicles = append([]VehicleRate{}, t.Vehicles...)
+	r.tariffs = append(r.tariffs, t)
+	return t, nil
+}
+
+func (r *MemoryTariffRepository) Delete(version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, t := range r.tariffs {
+		if t.Version != version {
+			continue
+		}
+		if !t.EffectiveAt.After(time.Now()) {
+			return ErrTariffInUse
+		}
+		r.tariffs = append(r.tariffs[:i], r.tariffs[i+1:]...)
+		return nil
+	}
+	return ErrTariffNotFound
+}
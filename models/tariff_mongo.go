@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MongoTariffRepository stores tariffs in the tariffs collection
+type MongoTariffRepository struct {
+	DB *mgo.Database
+}
+
+func NewMongoTariffRepository(db *mgo.Database) *MongoTariffRepository {
+	return &MongoTariffRepository{DB: db}
+}
+
+func (r *MongoTariffRepository) tariffs() *mgo.Collection {
+	return r.DB.C("tariffs")
+}
+
+func (r *MongoTariffRepository) List() (tariffs []Tariff, err error) {
+	tariffs = []Tariff{}
+	err = r.tariffs().Find(nil).Sort("_id").All(&tariffs)
+	return
+}
+
+func (r *MongoTariffRepository) Get(version int) (t Tariff, err error) {
+	err = r.tariffs().FindId(version).One(&t)
+	if err == mgo.ErrNotFound {
+		err = ErrTariffNotFound
+	}
+	return
+}
+
+func (r *MongoTariffRepository) Current(at time.Time) (t Tariff, err error) {
+	err = r.tariffs().Find(bson.M{"effective_at": bson.M{"$lte": at}}).Sort("-effective_at", "-_id").One(&t)
+	if err == mgo.ErrNotFound {
+		err = ErrTariffNotFound
+	}
+	return
+}
+
+// Create numbers the tariff after the highest stored version,
+// retrying when another tariff grabbed the same number
+func (r *MongoTariffRepository) Create(t Tariff) (Tariff, error) {
+	t.CreatedAt = time.Now()
+	for {
+		var last Tariff
+		err := r.tariffs().Find(nil).Sort("-_id").One(&last)
+		if err != nil && err != mgo.ErrNotFound {
+			return t, err
+		}
+		t.Version = last.Version + 1
+		err = r.tariffs().Insert(&t)
+		if !mgo.IsDup(err) {
+			return t, err
+		}
+	}
+}
+
+func (r *MongoTariffRepository) Delete(version int) error {
+	err := r.tariffs().Remove(bson.M{"_id": version, "effective_at": bson.M{"$gt": time.Now()}})
+	if err == mgo.ErrNotFound {
+		if _, err = r.Get(version); err != nil {
+			return err
+		}
+		return ErrTariffInUse
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrips(t *testing.T) {
+	rate := VehicleRate{MaxVolume: 12, MaxWeight: 1800}
+	tests := []struct {
+		load  Load
+		trips int
+	}{
+		{load: Load{}, trips: 1},
+		{load: Load{Volume: 12, Weight: 1800}, trips: 1},
+		{load: Load{Volume: 12.1, Weight: 100}, trips: 2},
+		{load: Load{Volume: 1, Weight: 5401}, trips: 4},
+		{load: Load{Volume: 30, Weight: 2000}, trips: 3},
+	}
+	for _, tt := range tests {
+		if got := rate.Trips(tt.load); got != tt.trips {
+			t.Errorf("Trips(%+v) = %d, want %d", tt.load, got, tt.trips)
+		}
+	}
+	if got := (VehicleRate{}).Trips(Load{Volume: 100, Weight: 10000}); got != 1 {
+		t.Errorf("Trips without limits = %d, want 1", got)
+	}
+}
+
+func TestSmallestFitting(t *testing.T) {
+	tariff := DefaultTariff()
+	tests := []struct {
+		load    Load
+		vehicle int
+	}{
+		{load: Load{Volume: 1, Weight: 100}, vehicle: 1},
+		{load: Load{Volume: 3, Weight: 700}, vehicle: 2},
+		{load: Load{Volume: 13, Weight: 100}, vehicle: 3},
+		{load: Load{Volume: 31}},
+	}
+	for _, tt := range tests {
+		rate, ok := tariff.SmallestFitting(tt.load)
+		if rate.VehicleType != tt.vehicle || ok != (tt.vehicle != 0) {
+			t.Errorf("SmallestFitting(%+v) = %d, %v, want %d", tt.load, rate.VehicleType, ok, tt.vehicle)
+		}
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	tariff := DefaultTariff()
+	o := Offer{VehicleType: 1}
+	if err := o.CheckCapacity(tariff, Load{Volume: 5, Weight: 300}); err != nil {
+		t.Fatal(err)
+	}
+	if o.Trips != 2 || o.SuggestedVehicle != 2 || o.Warning == "" {
+		t.Errorf("%d trips, suggested %d, warning %q", o.Trips, o.SuggestedVehicle, o.Warning)
+	}
+	// checking again for a smaller load clears the warning
+	if err := o.CheckCapacity(tariff, Load{Volume: 1, Weight: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if o.Trips != 1 || o.SuggestedVehicle != 0 || o.Warning != "" {
+		t.Errorf("%d trips, suggested %d, warning %q", o.Trips, o.SuggestedVehicle, o.Warning)
+	}
+	o = Offer{VehicleType: 3}
+	if err := o.CheckCapacity(tariff, Load{Volume: 31}); err != nil {
+		t.Fatal(err)
+	}
+	if o.Trips != 2 || o.SuggestedVehicle != 0 || o.Warning == "" {
+		t.Errorf("%d trips, suggested %d, warning %q", o.Trips, o.SuggestedVehicle, o.Warning)
+	}
+	if err := (&Offer{VehicleType: 9}).CheckCapacity(tariff, Load{}); err == nil {
+		t.Error("capacity of an unknown vehicle")
+	}
+}
+
+func TestCurrentTariff(t *testing.T) {
+	jan := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+	tariffs := []Tariff{
+		{Version: 1, EffectiveAt: jan},
+		{Version: 2, EffectiveAt: mar},
+		// created later for the same date, so it replaces version 2
+		{Version: 3, EffectiveAt: mar},
+		// created later but effective before version 2
+		{Version: 4, EffectiveAt: jan.AddDate(0, 1, 0)},
+	}
+	tests := []struct {
+		at      time.Time
+		version int
+		err     error
+	}{
+		{at: jan.Add(-time.Second), err: ErrTariffNotFound},
+		{at: jan, version: 1},
+		{at: jan.AddDate(0, 1, 0), version: 4},
+		{at: mar.Add(-time.Second), version: 4},
+		{at: mar, version: 3},
+		{at: mar.AddDate(1, 0, 0), version: 3},
+	}
+	for _, tt := range tests {
+		got, err := currentTariff(tariffs, tt.at)
+		if err != tt.err || got.Version != tt.version {
+			t.Errorf("at %v: version %d, %v, want %d, %v", tt.at, got.Version, err, tt.version, tt.err)
+		}
+	}
+}
+
+func TestMemoryTariffRepository(t *testing.T) {
+	r := NewMemoryTariffRepository()
+	now := time.Now()
+	for i, at := range []time.Time{now.Add(-time.Hour), now.Add(-time.Minute), now.Add(time.Hour)} {
+		created, err := r.Create(Tariff{EffectiveAt: at})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created.Version != i+1 || created.CreatedAt.IsZero() {
+			t.Errorf("tariff %d created as version %d at %v", i+1, created.Version, created.CreatedAt)
+		}
+	}
+	if current, err := r.Current(now); err != nil || current.Version != 2 {
+		t.Errorf("current version %d, %v, want 2", current.Version, err)
+	}
+	if err := r.Delete(2); err != ErrTariffInUse {
+		t.Errorf("err = %v deleting the tariff in effect, want %v", err, ErrTariffInUse)
+	}
+	if err := r.Delete(1); err != ErrTariffInUse {
+		t.Errorf("err = %v deleting a tariff that was in effect, want %v", err, ErrTariffInUse)
+	}
+	if err := r.Delete(3); err != nil {
+		t.Errorf("err = %v deleting a future tariff", err)
+	}
+	if err := r.Delete(3); err != ErrTariffNotFound {
+		t.Errorf("err = %v deleting a missing tariff, want %v", err, ErrTariffNotFound)
+	}
+	if list, _ := r.List(); len(list) != 2 {
+		t.Errorf("%d tariffs left, want 2", len(list))
+	}
+}
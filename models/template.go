@@ -0,0 +1,176 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+)
+
+var ErrTemplateNotFound = errors.New("Inventory template not found")
+
+// InventoryTemplate is a typical inventory for a home type
+// that new profiles can start from
+type InventoryTemplate struct {
+	ID        string `bson:"_id" json:"id"`
+	Name      string `bson:"name" json:"nome"`
+	Inventory []Room `bson:"inventory" json:"inventario"`
+}
+
+// TemplateRepository stores the inventory templates
+type TemplateRepository interface {
+	List() ([]InventoryTemplate, error)
+	Get(id string) (InventoryTemplate, error)
+	Save(t InventoryTemplate) error
+	Delete(id string) error
+}
+
+// Validate checks the id is a slug and the inventory could
+// be given to a profile. Every item references an item of the
+// catalog, so templates follow its volumes and weights
+func (t *InventoryTemplate) Validate(catalog Catalog) error {
+	if !slug.MatchString(t.ID) {
+		return errors.New("Template id must be lowercase letters, digits and dashes")
+	}
+	if t.Name == "" {
+		return errors.New("Template name is required")
+	}
+	if _, err := NewInventory(t.Inventory); err != nil {
+		return err
+	}
+	for _, room := range t.Inventory {
+		for _, box := range room.Boxes {
+			for _, item := range box.Items {
+				if item.CatalogID == "" {
+					return errors.New("Template items must reference the catalog")
+				}
+				if _, ok := catalog[item.CatalogID]; !ok {
+					return ErrCatalogItemNotFound
+				}
+				if item.Type == "" || item.Quantity < 1 {
+					return errors.New("Template items need a type and a positive quantity")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// DefaultTemplates is stored when there are no templates
+func DefaultTemplates() []InventoryTemplate {
+	return []InventoryTemplate{
+		{ID: "studio", Name: "Studio", Inventory: []Room{
+			{Name: "sala", Boxes: []Box{
+				templateBox("sofá", Item{Quantity: 1, Type: "sofa"}),
+				templateBox("tv", Item{Quantity: 1, Type: "tv"}),
+				templateBox("cama", Item{Quantity: 1, Type: "cama-casal"}, Item{Quantity: 1, Type: "colchao"}),
+			}},
+			{Name: "cozinha", Boxes: []Box{
+				templateBox("eletrodomésticos", Item{Quantity: 1, Type: "geladeira"}, Item{Quantity: 1, Type: "fogao"}),
+				templateBox("louças", Item{Quantity: 20, Type: "loucas"}),
+			}},
+		}},
+		{ID: "1-quarto", Name: "Apartamento de 1 quarto", Inventory: []Room{
+			{Name: "sala", Boxes: []Box{
+				templateBox("sofá", Item{Quantity: 1, Type: "sofa"}),
+				templateBox("tv", Item{Quantity: 1, Type: "tv"}),
+				templateBox("jantar", Item{Quantity: 1, Type: "mesa"}, Item{Quantity: 4, Type: "cadeira"}),
+			}},
+			{Name: "quarto", Boxes: []Box{
+				templateBox("cama", Item{Quantity: 1, Type: "cama-casal"}, Item{Quantity: 1, Type: "colchao"}),
+				templateBox("guarda-roupa", Item{Quantity: 1, Type: "guarda-roupa"}),
+				templateBox("roupas", Item{Quantity: 3, Type: "caixa"}),
+			}},
+			{Name: "cozinha", Boxes: []Box{
+				templateBox("eletrodomésticos", Item{Quantity: 1, Type: "geladeira"}, Item{Quantity: 1, Type: "fogao"}, Item{Quantity: 1, Type: "maquina-lavar"}),
+				templateBox("louças", Item{Quantity: 30, Type: "loucas"}),
+			}},
+		}},
+		{ID: "2-quartos", Name: "Apartamento de 2 quartos", Inventory: []Room{
+			{Name: "sala", Boxes: []Box{
+				templateBox("sofá", Item{Quantity: 1, Type: "sofa"}),
+				templateBox("tv", Item{Quantity: 1, Type: "tv"}),
+				templateBox("jantar", Item{Quantity: 1, Type: "mesa"}, Item{Quantity: 6, Type: "cadeira"}),
+				templateBox("livros", Item{Quantity: 3, Type: "caixa"}),
+			}},
+			{Name: "quarto", Boxes: []Box{
+				templateBox("cama", Item{Quantity: 1, Type: "cama-casal"}, Item{Quantity: 1, Type: "colchao"}),
+				templateBox("guarda-roupa", Item{Quantity: 1, Type: "guarda-roupa"}),
+				templateBox("roupas", Item{Quantity: 4, Type: "caixa"}),
+			}},
+			{Name: "quarto 2", Boxes: []Box{
+				templateBox("cama", Item{Quantity: 1, Type: "colchao"}, Item{Quantity: 1, Type: "moveis"}),
+				templateBox("guarda-roupa", Item{Quantity: 1, Type: "guarda-roupa"}),
+				templateBox("roupas", Item{Quantity: 3, Type: "caixa"}),
+			}},
+			{Name: "cozinha", Boxes: []Box{
+				templateBox("eletrodomésticos", Item{Quantity: 1, Type: "geladeira"}, Item{Quantity: 1, Type: "fogao"}, Item{Quantity: 1, Type: "maquina-lavar"}),
+				templateBox("louças", Item{Quantity: 40, Type: "loucas"}),
+			}},
+		}},
+		{ID: "casa-garagem", Name: "Casa com garagem", Inventory: []Room{
+			{Name: "sala", Boxes: []Box{
+				templateBox("sofá", Item{Quantity: 2, Type: "sofa"}),
+				templateBox("tv", Item{Quantity: 1, Type: "tv"}),
+				templateBox("jantar", Item{Quantity: 1, Type: "mesa"}, Item{Quantity: 6, Type: "cadeira"}),
+				templateBox("livros", Item{Quantity: 4, Type: "caixa"}),
+			}},
+			{Name: "quarto", Boxes: []Box{
+				templateBox("cama", Item{Quantity: 1, Type: "cama-casal"}, Item{Quantity: 1, Type: "colchao"}),
+				templateBox("guarda-roupa", Item{Quantity: 1, Type: "guarda-roupa"}),
+				templateBox("roupas", Item{Quantity: 4, Type: "caixa"}),
+			}},
+			{Name: "quarto 2", Boxes: []Box{
+				templateBox("cama", Item{Quantity: 1, Type: "cama-casal"}, Item{Quantity: 1, Type: "colchao"}),
+				templateBox("guarda-roupa", Item{Quantity: 1, Type: "guarda-roupa"}),
+				templateBox("roupas", Item{Quantity: 3, Type: "caixa"}),
+			}},
+			{Name: "cozinha", Boxes: []Box{
+				templateBox("eletrodomésticos", Item{Quantity: 1, Type: "geladeira"}, Item{Quantity: 1, Type: "fogao"}, Item{Quantity: 1, Type: "maquina-lavar"}),
+				templateBox("louças", Item{Quantity: 50, Type: "loucas"}),
+			}},
+			{Name: "garagem", Boxes: []Box{
+				templateBox("ferramentas", Item{Quantity: 4, Type: "caixa"}),
+				templateBox("diversos", Item{Quantity: 2, Type: "moveis"}),
+			}},
+		}},
+	}
+}
+
+func templateBox(label string, items ...Item) Box {
+	for i := range items {
+		items[i].CatalogID = items[i].Type
+	}
+	return Box{Label: label, Items: items}
+}
+
+// LoadTemplates reads a JSON array of inventory templates from a file
+func LoadTemplates(path string) (templates []InventoryTemplate, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &templates)
+	return
+}
+
+// SeedTemplates saves the given templates when there are none,
+// checking their items against the stored catalog
+func SeedTemplates(r TemplateRepository, catalog CatalogRepository, templates []InventoryTemplate) error {
+	existing, err := r.List()
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	items, err := catalog.List()
+	if err != nil {
+		return err
+	}
+	for _, t := range templates {
+		if err = t.Validate(NewCatalog(items)); err != nil {
+			return err
+		}
+		if err = r.Save(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
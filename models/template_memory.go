@@ -0,0 +1,59 @@
+package models
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryTemplateRepository keeps inventory templates in a map
+type MemoryTemplateRepository struct {
+	mu        sync.RWMutex
+	templates map[string]InventoryTemplate
+}
+
+func NewMemoryTemplateRepository() *MemoryTemplateRepository {
+	return &MemoryTemplateRepository{templates: make(map[string]InventoryTemplate)}
+}
+
+func (r *MemoryTemplateRepository) List() ([]InventoryTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	templates := make([]InventoryTemplate, 0, len(r.templates))
+	for _, t := range r.templates {
+		templates = append(templates, t.clone())
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates, nil
+}
+
+func (r *MemoryTemplateRepository) Get(id string) (InventoryTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.templates[id]
+	if !ok {
+		return t, ErrTemplateNotFound
+	}
+	return t.clone(), nil
+}
+
+func (r *MemoryTemplateRepository) Save(t InventoryTemplate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates[t.ID] = t.clone()
+	return nil
+}
+
+func (r *MemoryTemplateRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.templates[id]; !ok {
+		return ErrTemplateNotFound
+	}
+	delete(r.templates, id)
+	return nil
+}
+
+func (t InventoryTemplate) clone() InventoryTemplate {
+	t.Inventory = Profile{Inventory: t.Inventory}.Clone().Inventory
+	return t
+}
@@ -0,0 +1,43 @@
+package models
+
+import mgo "gopkg.in/mgo.v2"
+
+// MongoTemplateRepository stores inventory templates in the templates collection
+type MongoTemplateRepository struct {
+	DB *mgo.Database
+}
+
+func NewMongoTemplateRepository(db *mgo.Database) *MongoTemplateRepository {
+	return &MongoTemplateRepository{DB: db}
+}
+
+func (r *MongoTemplateRepository) templates() *mgo.Collection {
+	return r.DB.C("templates")
+}
+
+func (r *MongoTemplateRepository) List() (templates []InventoryTemplate, err error) {
+	templates = []InventoryTemplate{}
+	err = r.templates().Find(nil).Sort("_id").All(&templates)
+	return
+}
+
+func (r *MongoTemplateRepository) Get(id string) (t InventoryTemplate, err error) {
+	err = r.templates().FindId(id).One(&t)
+	if err == mgo.ErrNotFound {
+		err = ErrTemplateNotFound
+	}
+	return
+}
+
+func (r *MongoTemplateRepository) Save(t InventoryTemplate) (err error) {
+	_, err = r.templates().UpsertId(t.ID, &t)
+	return
+}
+
+func (r *MongoTemplateRepository) Delete(id string) (err error) {
+	err = r.templates().RemoveId(id)
+	if err == mgo.ErrNotFound {
+		err = ErrTemplateNotFound
+	}
+	return
+}
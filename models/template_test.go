@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestTemplateValidate(t *testing.T) {
+	catalog := NewCatalog(DefaultCatalog())
+	for _, template := range DefaultTemplates() {
+		if err := template.Validate(catalog); err != nil {
+			t.Errorf("default template %s: %v", template.ID, err)
+		}
+	}
+	template := func(rooms ...Room) InventoryTemplate {
+		return InventoryTemplate{ID: "studio", Name: "Studio", Inventory: rooms}
+	}
+	room := func(name string, items ...Item) Room {
+		return Room{Name: name, Boxes: []Box{{Items: items}}}
+	}
+	tests := []struct {
+		name     string
+		template InventoryTemplate
+		valid    bool
+		err      error
+	}{
+		{name: "catalog item", template: template(room("sala", Item{Type: "tv", CatalogID: "tv", Quantity: 1})), valid: true},
+		{name: "no catalog reference", template: template(room("sala", Item{Type: "tv", Quantity: 1}))},
+		{name: "unknown catalog item", template: template(room("sala", Item{Type: "piano", CatalogID: "piano", Quantity: 1})), err: ErrCatalogItemNotFound},
+		{name: "duplicate room", template: template(room("sala"), room(" sala ")), err: ErrRoomExists},
+		{name: "no quantity", template: template(room("sala", Item{Type: "tv", CatalogID: "tv"}))},
+		{name: "id not a slug", template: InventoryTemplate{ID: "Studio 1", Name: "Studio"}},
+	}
+	for _, tt := range tests {
+		err := tt.template.Validate(catalog)
+		if (err == nil) != tt.valid || (tt.err != nil && err != tt.err) {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
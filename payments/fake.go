@@ -0,0 +1,99 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/pix"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the
+// webhook body, hex encoded after "sha256="
+const SignatureHeader = "X-Signature"
+
+// Fake accepts every charge without moving money, for local
+// development. Pix charges carry a dynamic BR Code whose location
+// does not exist. Charges are paid by posting their event to the
+// webhook, signed with the secret:
+// {"id": "evt_1", "tipo": "charge.paid", "cobranca": "ch_...", "valor": 1692}
+type Fake struct {
+	secret   []byte
+	merchant pix.Merchant
+}
+
+func NewFake(secret []byte, merchant pix.Merchant) *Fake {
+	return &Fake{secret: secret, merchant: merchant}
+}
+
+func (f *Fake) CreateCharge(req ChargeRequest) (Charge, error) {
+	c := f.charge(Card, req)
+	if req.CardToken == "" {
+		c.CheckoutURL = "https://pagamentos.invalid/checkout/" + c.ID
+	}
+	log.Printf("fake card charge %s of %.2f for %s", c.ID, c.Amount, req.Reference)
+	return c, nil
+}
+
+func (f *Fake) CreatePixCharge(req ChargeRequest) (Charge, error) {
+	c := f.charge(Pix, req)
+	code, err := f.merchant.Dynamic("pagamentos.invalid/pix/v2/" + c.ID)
+	if err != nil {
+		return c, err
+	}
+	c.PixCode = code
+	c.ExpiresAt = time.Now().Add(time.Hour)
+	log.Printf("fake Pix charge %s of %.2f for %s", c.ID, c.Amount, req.Reference)
+	return c, nil
+}
+
+func (f *Fake) Refund(chargeID string, amount float64) (Refund, error) {
+	r := Refund{ID: newID("re_"), ChargeID: chargeID, Amount: amount}
+	log.Printf("fake refund %s of %.2f from %s", r.ID, amount, chargeID)
+	return r, nil
+}
+
+// fakeEvent is the webhook body of the fake provider
+type fakeEvent struct {
+	ID       string    `json:"id"`
+	Type     EventType `json:"tipo"`
+	ChargeID string    `json:"cobranca"`
+	Amount   float64   `json:"valor"`
+}
+
+func (f *Fake) Webhook(body []byte, header http.Header) (e Event, err error) {
+	signature, err := hex.DecodeString(strings.TrimPrefix(header.Get(SignatureHeader), "sha256="))
+	if err != nil || !hmac.Equal(signature, f.Sign(body)) {
+		return e, ErrInvalidSignature
+	}
+	var fe fakeEvent
+	if err = json.Unmarshal(body, &fe); err != nil {
+		return
+	}
+	return Event(fe), nil
+}
+
+// Sign returns the HMAC-SHA256 of a webhook body
+func (f *Fake) Sign(body []byte) []byte {
+	m := hmac.New(sha256.New, f.secret)
+	m.Write(body)
+	return m.Sum(nil)
+}
+
+func (f *Fake) charge(method Method, req ChargeRequest) Charge {
+	return Charge{ID: newID("ch_"), Method: method, Amount: req.Amount}
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return prefix + hex.EncodeToString(b)
+}
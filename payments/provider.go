@@ -0,0 +1,98 @@
+// Package payments collects the price of accepted offers
+// through a payment provider
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/MudaeH5A/4thinkbe/pix"
+)
+
+var (
+	ErrInvalidSignature = errors.New("Invalid webhook signature")
+	ErrChargeNotFound   = errors.New("Charge not found")
+)
+
+// Method is how the customer pays
+type Method string
+
+const (
+	Card Method = "cartao"
+	Pix  Method = "pix"
+)
+
+// ChargeRequest asks the provider to collect an amount
+type ChargeRequest struct {
+	// Reference identifies what is paid, the quote ID
+	Reference   string
+	Amount      float64
+	Description string
+	Payer       string
+	// CardToken is the card tokenized by the provider in the app
+	CardToken string
+}
+
+// Charge is a payment created at the provider, pending
+// until a webhook tells it was paid
+type Charge struct {
+	ID     string
+	Method Method
+	Amount float64
+	// CheckoutURL is where card charges without token are paid
+	CheckoutURL string
+	// PixCode is the copy and paste BR Code of Pix charges
+	PixCode   string
+	ExpiresAt time.Time
+}
+
+// Refund gives back part or all of a paid charge
+type Refund struct {
+	ID       string
+	ChargeID string
+	Amount   float64
+}
+
+// EventType is what happened to a charge
+type EventType string
+
+const (
+	ChargePaid     EventType = "charge.paid"
+	ChargeFailed   EventType = "charge.failed"
+	ChargeRefunded EventType = "charge.refunded"
+)
+
+// Event is a provider callback. Providers deliver events at
+// least once, so the same ID may arrive more than once
+type Event struct {
+	ID       string
+	Type     EventType
+	ChargeID string
+	Amount   float64
+}
+
+// PaymentProvider creates charges and refunds, and tells
+// their outcome through signed webhooks
+type PaymentProvider interface {
+	CreateCharge(req ChargeRequest) (Charge, error)
+	CreatePixCharge(req ChargeRequest) (Charge, error)
+	Refund(chargeID string, amount float64) (Refund, error)
+	// Webhook checks the signature of a callback
+	// and returns the event it carries
+	Webhook(body []byte, header http.Header) (Event, error)
+}
+
+// FromEnv picks the provider named by PAYMENT_PROVIDER, "fake"
+// by default, verifying its webhooks with secret. Pix charges
+// are paid to the merchant account
+func FromEnv(secret []byte, merchant pix.Merchant) (PaymentProvider, error) {
+	switch name := os.Getenv("PAYMENT_PROVIDER"); name {
+	case "", "fake":
+		return NewFake(secret, merchant), nil
+	default:
+		return nil, fmt.Errorf("Unknown payment provider %q", name)
+	}
+}
@@ -0,0 +1,138 @@
+// Package pix builds the BR Code of Pix payments, the EMV-MPM
+// payload Brazilian bank apps read from a QR code or paste
+package pix
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrNoKey     = errors.New("Pix key is not configured")
+	ErrTxID      = errors.New("Pix transaction ID must have up to 25 letters and digits")
+	ErrTooLong   = errors.New("Pix key or location is too long")
+	ErrBadAmount = errors.New("Pix amount must be positive")
+)
+
+// gui identifies the Pix arrangement in the merchant account
+const gui = "br.gov.bcb.pix"
+
+var txID = regexp.MustCompile(`^[A-Za-z0-9]{1,25}$`)
+
+// Merchant receives the payments
+type Merchant struct {
+	// Key is the Pix key of the account (CNPJ, e-mail, phone or random key)
+	Key  string
+	Name string
+	City string
+}
+
+// MerchantFromEnv reads PIX_KEY, PIX_MERCHANT_NAME and PIX_MERCHANT_CITY
+func MerchantFromEnv() Merchant {
+	m := Merchant{
+		Key:  os.Getenv("PIX_KEY"),
+		Name: os.Getenv("PIX_MERCHANT_NAME"),
+		City: os.Getenv("PIX_MERCHANT_CITY"),
+	}
+	if m.Name == "" {
+		m.Name = "Mudae"
+	}
+	if m.City == "" {
+		m.City = "Rio de Janeiro"
+	}
+	return m
+}
+
+// Static returns the BR Code paying amount to the merchant key.
+// The transaction ID lets the payment be matched with what it
+// pays, "***" leaves it out. A zero amount is typed by the payer
+func (m Merchant) Static(amount float64, id, description string) (string, error) {
+	if m.Key == "" {
+		return "", ErrNoKey
+	}
+	if id != "***" && !txID.MatchString(id) {
+		return "", ErrTxID
+	}
+	if amount < 0 {
+		return "", ErrBadAmount
+	}
+	account := field("00", gui) + field("01", m.Key)
+	// the description only goes in when it fits the account field
+	description = ascii(description)
+	if room := 99 - len(account) - 4; description != "" && room > 0 {
+		if len(description) > room {
+			description = description[:room]
+		}
+		account += field("02", description)
+	}
+	return m.payload("", account, amount, id)
+}
+
+// Dynamic returns the BR Code of a charge created at the
+// payment provider, which serves its amount and details
+// from location, a URL without the scheme
+func (m Merchant) Dynamic(location string) (string, error) {
+	location = strings.TrimPrefix(strings.TrimPrefix(location, "https://"), "http://")
+	account := field("00", gui) + field("25", location)
+	return m.payload("12", account, 0, "***")
+}
+
+// payload writes the fields in order and appends the CRC16
+func (m Merchant) payload(initiation, account string, amount float64, id string) (string, error) {
+	if len(account) > 99 {
+		return "", ErrTooLong
+	}
+	var b strings.Builder
+	b.WriteString(field("00", "01"))
+	if initiation != "" {
+		b.WriteString(field("01", initiation))
+	}
+	b.WriteString(field("26", account))
+	b.WriteString(field("52", "0000"))
+	b.WriteString(field("53", "986"))
+	if amount > 0 {
+		b.WriteString(field("54", fmt.Sprintf("%.2f", amount)))
+	}
+	b.WriteString(field("58", "BR"))
+	b.WriteString(field("59", truncate(ascii(m.Name), 25)))
+	b.WriteString(field("60", truncate(ascii(m.City), 15)))
+	b.WriteString(field("62", field("05", id)))
+	b.WriteString("6304")
+	code := b.String()
+	return code + fmt.Sprintf("%04X", crc16(code)), nil
+}
+
+// field writes an EMV data object: ID, two digit length and value
+func field(id, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "ê", "e", "è", "e", "í", "i", "ó", "o", "ô", "o", "õ", "o",
+	"ú", "u", "ü", "u", "ç", "c",
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "É", "E", "Ê", "E", "Í", "I",
+	"Ó", "O", "Ô", "O", "Õ", "O", "Ú", "U", "Ç", "C",
+)
+
+// ascii drops accents and any other character bank
+// apps may not read, keeping lengths in bytes exact
+func ascii(s string) string {
+	s = accents.Replace(s)
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
@@ -0,0 +1,99 @@
+package pix
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// reference is the static BR Code example of the Pix
+// specification of the Banco Central do Brasil
+const reference = "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D"
+
+func TestCRC16(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    uint16
+	}{
+		// check value of CRC-16/CCITT-FALSE
+		{"123456789", 0x29B1},
+		{"", 0xFFFF},
+		{strings.TrimSuffix(reference, "1D3D"), 0x1D3D},
+	}
+	for _, tt := range tests {
+		if got := crc16(tt.payload); got != tt.want {
+			t.Errorf("crc16(%q) = %04X, want %04X", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestStatic(t *testing.T) {
+	m := Merchant{Key: "123e4567-e12b-12d1-a456-426655440000", Name: "Fulano de Tal", City: "BRASILIA"}
+	tests := []struct {
+		name        string
+		merchant    Merchant
+		amount      float64
+		id          string
+		description string
+		want        string
+		err         error
+	}{
+		{name: "reference", merchant: m, id: "***", want: reference},
+		{name: "amount", merchant: m, amount: 1546, id: "***", want: "54071546.00"},
+		{name: "transaction id", merchant: m, id: "5c1d2e3f", want: "621205085c1d2e3f"},
+		{name: "description", merchant: m, id: "***", description: "Mudança", want: "0207Mudanca"},
+		{name: "accents", merchant: Merchant{Key: "k", Name: "Mudaê", City: "São Paulo"}, id: "***", want: "5905Mudae6009Sao Paulo"},
+		{name: "no key", merchant: Merchant{Name: "Mudae", City: "Rio"}, id: "***", err: ErrNoKey},
+		{name: "bad transaction id", merchant: m, id: "quote-1", err: ErrTxID},
+		{name: "long transaction id", merchant: m, id: strings.Repeat("a", 26), err: ErrTxID},
+		{name: "negative amount", merchant: m, amount: -1, id: "***", err: ErrBadAmount},
+		{name: "long key", merchant: Merchant{Key: strings.Repeat("k", 80)}, id: "***", err: ErrTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.merchant.Static(tt.amount, tt.id, tt.description)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("code %s does not contain %s", got, tt.want)
+			}
+			checkCRC(t, got)
+		})
+	}
+}
+
+func TestDynamic(t *testing.T) {
+	m := Merchant{Key: "k", Name: "Mudae", City: "Rio de Janeiro"}
+	got, err := m.Dynamic("https://pagamentos.invalid/pix/v2/ch_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"000201010212",
+		"26520014br.gov.bcb.pix2530pagamentos.invalid/pix/v2/ch_1",
+		"62070503***",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("code %s does not contain %s", got, want)
+		}
+	}
+	if strings.Contains(got, "5404") || strings.Contains(got, "0101k") {
+		t.Errorf("dynamic code %s carries the amount or the key", got)
+	}
+	checkCRC(t, got)
+	if _, err = m.Dynamic(strings.Repeat("x", 90)); err != ErrTooLong {
+		t.Errorf("err = %v, want %v", err, ErrTooLong)
+	}
+}
+
+func checkCRC(t *testing.T, code string) {
+	t.Helper()
+	body, sum := code[:len(code)-4], code[len(code)-4:]
+	if want := fmt.Sprintf("%04X", crc16(body)); sum != want {
+		t.Errorf("code %s ends with CRC %s, want %s", code, sum, want)
+	}
+}
@@ -0,0 +1,19 @@
+package pix
+
+// crc16 is the CRC-16/CCITT-FALSE checksum closing every BR Code:
+// polynomial 0x1021, initial value 0xFFFF, computed over the
+// payload up to and including the ID and length of the CRC field
+func crc16(payload string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(payload); i++ {
+		crc ^= uint16(payload[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}